@@ -0,0 +1,130 @@
+package gocache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// 每轮采样数量，与 redis ACTIVE_EXPIRE_CYCLE_KEYS_PER_LOOP 一致
+	defaultActiveExpireSamples = 20
+	// 每次执行的默认时间预算
+	defaultActiveExpireBudget = 25 * time.Millisecond
+)
+
+// ttlKeySet 所有 TTL key 的集合，支持 O(1) 随机采样和删除
+type ttlKeySet struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+	keys  []string
+	index map[string]int
+}
+
+func newTTLKeySet() *ttlKeySet {
+	s := ttlKeySet{
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		keys:  make([]string, 0),
+		index: make(map[string]int),
+	}
+	return &s
+}
+
+func (s *ttlKeySet) add(key string) {
+	s.mutex.Lock()
+	if _, ok := s.index[key]; !ok {
+		s.index[key] = len(s.keys)
+		s.keys = append(s.keys, key)
+	}
+	s.mutex.Unlock()
+}
+
+// remove 与最后一个元素交换后删除
+func (s *ttlKeySet) remove(key string) {
+	s.mutex.Lock()
+	if i, ok := s.index[key]; ok {
+		last := len(s.keys) - 1
+		s.keys[i] = s.keys[last]
+		s.index[s.keys[i]] = i
+		s.keys = s.keys[:last]
+		delete(s.index, key)
+	}
+	s.mutex.Unlock()
+}
+
+// sample 随机抽取 n 个 key，数量不足 n 时返回全部
+func (s *ttlKeySet) sample(n int) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.keys) <= n {
+		return append([]string(nil), s.keys...)
+	}
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = s.keys[s.rand.Intn(len(s.keys))]
+	}
+	return keys
+}
+
+func (s *ttlKeySet) flush() {
+	s.mutex.Lock()
+	s.keys = make([]string, 0)
+	s.index = make(map[string]int)
+	s.mutex.Unlock()
+}
+
+// AutoActiveExpireKey 主动过期，需要开启 Config.ActiveExpire，未开启时返回 ErrActiveExpireDisabled，与 AutoCleanExpireKey 二选一
+// 每隔 interval 随机采样 TTL key 删除已过期的，采样中过期比例超过 25% 时继续下一轮，直到用完时间预算
+// 不遍历全部数据，适合数据量大但 TTL key 较少的场景，不会长时间占用锁
+func (mem *MemCache) AutoActiveExpireKey(interval time.Duration) error {
+	if mem.ttlKeys == nil {
+		return ErrActiveExpireDisabled
+	}
+	mem.activeOnce.Do(func() {
+		ticker := mem.clock.NewTicker(interval)
+		go func() {
+			for {
+				select {
+				case <-mem.exit:
+					ticker.Stop()
+					return
+				case <-ticker.Chan():
+					mem.activeExpireCycle()
+				}
+			}
+		}()
+	})
+	return nil
+}
+
+// activeExpireCycle 返回删除数量
+func (mem *MemCache) activeExpireCycle() int {
+	count := 0
+	// 时间预算限制的是实际执行时间，使用系统时间
+	deadline := time.Now().Add(mem.activeExpireBudget)
+	for {
+		keys := mem.ttlKeys.sample(mem.activeExpireSamples)
+		if len(keys) == 0 {
+			return count
+		}
+
+		expired := 0
+		now := mem.now()
+		for _, key := range keys {
+			v, ok := mem.store.Load(key)
+			if !ok {
+				mem.ttlKeys.remove(key)
+				continue
+			}
+			if v.(expireValue).isExpire(now) && mem.removeExpired(key, now) {
+				expired++
+			}
+		}
+		count += expired
+
+		if expired*4 <= len(keys) || time.Now().After(deadline) {
+			return count
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gocache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLKeySet(t *testing.T) {
+	s := newTTLKeySet()
+	for i := 0; i < 100; i++ {
+		s.add(fmt.Sprintf("%d", i))
+	}
+	s.add("0")
+	s.remove("50")
+	if len(s.keys) != 99 || len(s.index) != 99 {
+		t.Fatal("set size error ", len(s.keys))
+		return
+	}
+	for _, key := range s.sample(20) {
+		if key == "50" {
+			t.Fatal("removed key should not sampled")
+			return
+		}
+	}
+	if len(s.sample(200)) != 99 {
+		t.Fatal("sample all error")
+		return
+	}
+}
+
+func TestMemCacheImpl_ActiveExpire(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		ActiveExpire: true,
+		Clock:        clock,
+	})
+	for i := 0; i < 1000; i++ {
+		_ = cache.Set(fmt.Sprintf("set-%d", i), i)
+	}
+	for i := 0; i < 100; i++ {
+		cache.setValue(fmt.Sprintf("expired-%d", i), expireValue{Value: i, Expire: cache.now() - 1})
+	}
+
+	if count := cache.activeExpireCycle(); count != 100 {
+		t.Fatal("active expire count error ", count)
+		return
+	}
+	if cache.Size() != 1000 {
+		t.Fatal("size error ", cache.Size())
+		return
+	}
+
+	if err := cache.AutoActiveExpireKey(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer cache.Close()
+	_ = cache.SetWithTTL("ttl", "1", 20*time.Millisecond)
+
+	clock.Advance(100 * time.Millisecond)
+	waitFor(func() bool { return cache.Size() == 1000 })
+
+	if cache.Size() != 1000 {
+		t.Fatal("AutoActiveExpireKey error ", cache.Size())
+		return
+	}
+}
+
+func TestMemCacheImpl_ActiveExpireDisabled(t *testing.T) {
+	cache := NewRWMapCache()
+	defer cache.Close()
+	if err := cache.AutoActiveExpireKey(100 * time.Millisecond); err != ErrActiveExpireDisabled {
+		t.Fatal("active expire should disabled ", err)
+		return
+	}
+}
@@ -0,0 +1,327 @@
+package gocache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// write-behind 默认批量写入间隔
+	defaultWriteBehindInterval = time.Second
+	// write-behind 写入失败默认重试次数
+	defaultWriteBehindRetries = 3
+)
+
+// Backend 缓存后面的持久化存储，key 不存在时 Get 返回 ErrNotFound，GetMany 不返回不存在的 key
+type Backend interface {
+	Get(ctx context.Context, key string) (value interface{}, err error)
+	Put(ctx context.Context, key string, value interface{}) error
+	Delete(ctx context.Context, key string) error
+	GetMany(ctx context.Context, keys []string) (map[string]interface{}, error)
+	PutMany(ctx context.Context, values map[string]interface{}) error
+	DeleteMany(ctx context.Context, keys []string) error
+}
+
+// BackendMode 与 Backend 同步的方式，可以组合，例如 BackendReadThrough | BackendWriteBehind
+type BackendMode int
+
+const (
+	// BackendReadThrough 读取未命中时从 Backend 加载并写入缓存，不存在时按 Config.NegativeTTL 缓存
+	BackendReadThrough BackendMode = 1 << iota
+	// BackendWriteThrough Set、Delete 先写入 Backend，成功后再写入缓存
+	// Backend 是数据源，缓存拒绝写入(超过容量等)时不返回错误，删除缓存中的旧值
+	BackendWriteThrough
+	// BackendWriteBehind Set、Delete 先写入缓存，按 Config.WriteBehindInterval 批量异步写入 Backend，失败重试，Close 时写入
+	// 缓存拒绝写入时同 BackendWriteThrough，仍然写入 Backend
+	// 与 BackendWriteThrough 同时设置时按 BackendWriteThrough 处理
+	BackendWriteBehind
+)
+
+// write 公开的写入方法使用，按 BackendMode 写入 Backend，内部加载的值不写回 Backend
+// 写入 Backend 时缓存拒绝写入不返回错误，删除缓存中的旧值，避免读到与 Backend 不一致的值
+func (mem *MemCache) write(key string, ev expireValue) error {
+	if mem.backendMode&BackendWriteThrough != 0 {
+		if err := mem.backend.Put(context.Background(), key, ev.Value); err != nil {
+			return err
+		}
+	}
+	err := mem.set(key, ev)
+	if !mem.writesBackend() {
+		return err
+	}
+	if err != nil {
+		mem.removeValue(key, RemovalReplaced)
+	}
+	if mem.behind != nil {
+		mem.behind.put(key, ev.Value)
+	}
+	return nil
+}
+
+// writesBackend 是否开启 write-through 或 write-behind
+func (mem *MemCache) writesBackend() bool {
+	return mem.backendMode&BackendWriteThrough != 0 || mem.behind != nil
+}
+
+// deleteBackend Delete 使用，write-through 失败时只记录日志，缓存仍然删除
+func (mem *MemCache) deleteBackend(key string) {
+	if mem.backendMode&BackendWriteThrough != 0 {
+		if err := mem.backend.Delete(context.Background(), key); err != nil {
+			log.Printf("Delete: backend delete key %s error, %v\n", key, err)
+		}
+	}
+	if mem.behind != nil {
+		mem.behind.delete(key)
+	}
+}
+
+// readThrough 从 Backend 加载，与 GetOrLoad 共享同一个 key 的加载，失败时返回不存在
+func (mem *MemCache) readThrough(key string) (expireValue, bool) {
+	ctx := context.Background()
+	v, err := mem.loads.do(ctx, key, func() (interface{}, error) {
+		if ev, ok := mem.peek(key); ok {
+			if ev.Tombstone {
+				return nil, ErrNotFound
+			}
+			return loadResult{value: ev.Value}, nil
+		}
+		value, err := mem.backend.Get(ctx, key)
+		if err == ErrNotFound {
+			return nil, mem.setNotFound(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return loadResult{value: value}, mem.set(key, mem.ttlValue(key, value, mem.backendTTL))
+	})
+	r, ok := v.(loadResult)
+	if !ok {
+		return expireValue{}, false
+	}
+	if err == nil {
+		if ev, ok := mem.peek(key); ok && !ev.Tombstone {
+			return ev, true
+		}
+	}
+	// 写入缓存失败，返回加载的值
+	return expireValue{Value: r.value, Expire: -1}, true
+}
+
+// writeBehind 等待写入 Backend 的 key，同一个 key 只保留最后一次操作
+type writeBehind struct {
+	backend Backend
+	retries int
+
+	mutex   sync.Mutex
+	pending map[string]behindOp
+
+	// 同时只执行一次 flush
+	flushMutex sync.Mutex
+	// 后台 goroutine 退出后关闭
+	done chan struct{}
+}
+
+type behindOp struct {
+	value    interface{}
+	delete   bool
+	attempts int
+}
+
+func newWriteBehind(backend Backend, retries int) *writeBehind {
+	if retries <= 0 {
+		retries = defaultWriteBehindRetries
+	}
+	return &writeBehind{
+		backend: backend,
+		retries: retries,
+		pending: make(map[string]behindOp),
+		done:    make(chan struct{}),
+	}
+}
+
+func (w *writeBehind) put(key string, value interface{}) {
+	w.mutex.Lock()
+	w.pending[key] = behindOp{value: value}
+	w.mutex.Unlock()
+}
+
+func (w *writeBehind) delete(key string) {
+	w.mutex.Lock()
+	w.pending[key] = behindOp{delete: true}
+	w.mutex.Unlock()
+}
+
+func (w *writeBehind) size() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.pending)
+}
+
+// flush 批量写入和删除，失败的 key 在下次 flush 时重试，超过重试次数丢弃
+func (w *writeBehind) flush() {
+	w.flushMutex.Lock()
+	defer w.flushMutex.Unlock()
+
+	w.mutex.Lock()
+	ops := w.pending
+	w.pending = make(map[string]behindOp)
+	w.mutex.Unlock()
+
+	puts := make(map[string]interface{})
+	deletes := make([]string, 0)
+	for key, op := range ops {
+		if op.delete {
+			deletes = append(deletes, key)
+		} else {
+			puts[key] = op.value
+		}
+	}
+
+	ctx := context.Background()
+	if len(puts) > 0 {
+		if err := w.backend.PutMany(ctx, puts); err != nil {
+			keys := make([]string, 0, len(puts))
+			for key := range puts {
+				keys = append(keys, key)
+			}
+			w.retry(ops, keys, err)
+		}
+	}
+	if len(deletes) > 0 {
+		if err := w.backend.DeleteMany(ctx, deletes); err != nil {
+			w.retry(ops, deletes, err)
+		}
+	}
+}
+
+// retry 失败的 key 重新加入等待，期间有新的操作时以新的为准
+func (w *writeBehind) retry(ops map[string]behindOp, keys []string, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, key := range keys {
+		if _, ok := w.pending[key]; ok {
+			continue
+		}
+		op := ops[key]
+		op.attempts++
+		if op.attempts > w.retries {
+			log.Printf("WriteBehind: drop key %s after %d attempts, %v\n", key, op.attempts, err)
+			continue
+		}
+		w.pending[key] = op
+	}
+}
+
+// drain Close 时写入所有等待的 key，失败时立即重试
+func (w *writeBehind) drain() {
+	for i := 0; i <= w.retries && w.size() > 0; i++ {
+		w.flush()
+	}
+}
+
+func (mem *MemCache) startWriteBehind(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWriteBehindInterval
+	}
+	w := mem.behind
+	ticker := mem.clock.NewTicker(interval)
+	go func() {
+		defer close(w.done)
+		for {
+			select {
+			case <-mem.exit:
+				ticker.Stop()
+				return
+			case <-ticker.Chan():
+				w.flush()
+			}
+		}
+	}()
+}
+
+// MapBackend 内存实现的 Backend，用于测试
+type MapBackend struct {
+	mutex  sync.RWMutex
+	values map[string]interface{}
+	err    error
+}
+
+func NewMapBackend() *MapBackend {
+	return &MapBackend{values: make(map[string]interface{})}
+}
+
+// SetError 设置后所有操作都返回 err，nil 恢复正常，用于模拟 Backend 故障
+func (b *MapBackend) SetError(err error) {
+	b.mutex.Lock()
+	b.err = err
+	b.mutex.Unlock()
+}
+
+func (b *MapBackend) Len() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.values)
+}
+
+func (b *MapBackend) Get(ctx context.Context, key string) (interface{}, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.err != nil {
+		return nil, b.err
+	}
+	v, ok := b.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+func (b *MapBackend) Put(ctx context.Context, key string, value interface{}) error {
+	return b.PutMany(ctx, map[string]interface{}{key: value})
+}
+
+func (b *MapBackend) Delete(ctx context.Context, key string) error {
+	return b.DeleteMany(ctx, []string{key})
+}
+
+func (b *MapBackend) GetMany(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.err != nil {
+		return nil, b.err
+	}
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := b.values[key]; ok {
+			values[key] = v
+		}
+	}
+	return values, nil
+}
+
+func (b *MapBackend) PutMany(ctx context.Context, values map[string]interface{}) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	for k, v := range values {
+		b.values[k] = v
+	}
+	return nil
+}
+
+func (b *MapBackend) DeleteMany(ctx context.Context, keys []string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	for _, key := range keys {
+		delete(b.values, key)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countBackend 统计 Get、PutMany 调用次数
+type countBackend struct {
+	*MapBackend
+	gets int64
+	puts int64
+}
+
+func (b *countBackend) PutMany(ctx context.Context, values map[string]interface{}) error {
+	atomic.AddInt64(&b.puts, 1)
+	return b.MapBackend.PutMany(ctx, values)
+}
+
+func (b *countBackend) Get(ctx context.Context, key string) (interface{}, error) {
+	atomic.AddInt64(&b.gets, 1)
+	return b.MapBackend.Get(ctx, key)
+}
+
+func TestMemCacheImpl_BackendReadThrough(t *testing.T) {
+	backend := &countBackend{MapBackend: NewMapBackend()}
+	_ = backend.Put(context.Background(), "a", "1")
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Backend:     backend,
+		BackendMode: BackendReadThrough,
+		BackendTTL:  time.Minute,
+		NegativeTTL: time.Minute,
+	})
+
+	v, ok := cache.Get("a")
+	if !ok || v.(string) != "1" {
+		t.Fatal("read through error ", v)
+		return
+	}
+	if ttl, ok := cache.TTL("a"); !ok || ttl <= 59*time.Second {
+		t.Fatal("read through ttl error ", ttl)
+		return
+	}
+	backend.SetError(errors.New("backend down"))
+	if v, ok := cache.Get("a"); !ok || v.(string) != "1" || backend.gets != 1 {
+		t.Fatal("should read from cache ", v, backend.gets)
+		return
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("backend error should not exists")
+		return
+	}
+	backend.SetError(nil)
+
+	// 不存在的 key 缓存 NegativeTTL
+	for i := 0; i < 3; i++ {
+		if _, ok := cache.Get("missing"); ok {
+			t.Fatal("missing should not exists")
+			return
+		}
+	}
+	if backend.gets != 3 {
+		t.Fatal("missing should cached ", backend.gets)
+		return
+	}
+
+	// 只读时写入不影响 Backend
+	_ = cache.Set("c", "3")
+	if backend.Len() != 1 {
+		t.Fatal("read through should not write backend ", backend.Len())
+		return
+	}
+}
+
+func TestMemCacheImpl_BackendReadThroughGetOrLoad(t *testing.T) {
+	backend := &countBackend{MapBackend: NewMapBackend()}
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Backend:     backend,
+		BackendMode: BackendReadThrough,
+		NegativeTTL: time.Minute,
+	})
+
+	calls := 0
+	v, err := cache.GetOrLoad(context.Background(), "a", func(ctx context.Context) (interface{}, time.Duration, error) {
+		calls++
+		return "1", time.Minute, nil
+	})
+	if err != nil || v.(string) != "1" || calls != 1 {
+		t.Fatal("GetOrLoad should call loader ", v, err, calls)
+		return
+	}
+	if backend.gets != 0 {
+		t.Fatal("GetOrLoad should not read through ", backend.gets)
+		return
+	}
+	if v, ok := cache.Get("a"); !ok || v.(string) != "1" {
+		t.Fatal("loaded value should cached ", v)
+		return
+	}
+
+	values, errs := cache.GetManyOrLoad(context.Background(), []string{"a", "b"}, func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		calls++
+		return map[string]BatchResult{"b": {Value: "2", TTL: time.Minute}}, nil
+	})
+	if len(errs) != 0 || values["a"].(string) != "1" || values["b"].(string) != "2" || calls != 2 {
+		t.Fatal("GetManyOrLoad should call batch loader ", values, errs, calls)
+		return
+	}
+	if backend.gets != 0 {
+		t.Fatal("GetManyOrLoad should not read through ", backend.gets)
+		return
+	}
+}
+
+func TestMemCacheImpl_BackendWriteThrough(t *testing.T) {
+	backend := NewMapBackend()
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Backend:     backend,
+		BackendMode: BackendReadThrough | BackendWriteThrough,
+	})
+
+	if err := cache.SetWithTTL("a", "1", time.Minute); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if v, err := backend.Get(context.Background(), "a"); err != nil || v.(string) != "1" {
+		t.Fatal("write through error ", v, err)
+		return
+	}
+
+	errDown := errors.New("backend down")
+	backend.SetError(errDown)
+	if err := cache.Set("a", "2"); err != errDown {
+		t.Fatal("should return backend error ", err)
+		return
+	}
+	if v, _ := cache.Get("a"); v.(string) != "1" {
+		t.Fatal("cache should not update when backend failed ", v)
+		return
+	}
+	backend.SetError(nil)
+
+	cache.Delete("a")
+	if backend.Len() != 0 {
+		t.Fatal("delete should write through")
+		return
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("a should deleted")
+		return
+	}
+}
+
+func TestMemCacheImpl_BackendWriteRejected(t *testing.T) {
+	for _, mode := range []BackendMode{BackendWriteThrough, BackendWriteBehind} {
+		backend := NewMapBackend()
+		cache := NewRWMapCacheWithConfig(Config{
+			LimitSize:   1,
+			LimitBytes:  100,
+			Backend:     backend,
+			BackendMode: mode,
+		})
+
+		// 缓存容量不足，Backend 是数据源，不返回错误
+		_ = cache.Set("a", "1")
+		if err := cache.Set("b", "2"); err != nil {
+			t.Fatal("rejected by cache should not error ", mode, err)
+			return
+		}
+		if err := cache.MSet(map[string]interface{}{"c": "3", "d": "4"}, time.Minute); err != nil {
+			t.Fatal("rejected by cache should not error ", mode, err)
+			return
+		}
+		// 缓存拒绝覆盖时删除旧值
+		if err := cache.Set("a", string(make([]byte, 200))); err != nil {
+			t.Fatal("rejected by cache should not error ", mode, err)
+			return
+		}
+		if _, ok := cache.Get("a"); ok {
+			t.Fatal("rejected key should removed from cache ", mode)
+			return
+		}
+
+		cache.Close()
+		if backend.Len() != 4 {
+			t.Fatal("backend should have all keys ", mode, backend.Len())
+			return
+		}
+	}
+}
+
+func TestMemCacheImpl_BackendWriteBehind(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	backend := &countBackend{MapBackend: NewMapBackend()}
+	_ = backend.MapBackend.Put(context.Background(), "c", "3")
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:           -1,
+		Clock:               clock,
+		Backend:             backend,
+		BackendMode:         BackendWriteBehind,
+		WriteBehindInterval: time.Second,
+		WriteBehindRetries:  1,
+	})
+
+	_ = cache.Set("a", "1")
+	_ = cache.Set("b", "2")
+	_ = cache.Set("b", "22")
+	cache.Delete("c")
+	if backend.Len() != 1 {
+		t.Fatal("write behind should async")
+		return
+	}
+
+	clock.Advance(time.Second)
+	if !waitFor(func() bool { return backend.Len() == 2 }) {
+		t.Fatal("write behind flush error ", backend.Len())
+		return
+	}
+	if v, _ := backend.Get(context.Background(), "b"); v.(string) != "22" {
+		t.Fatal("should write last value ", v)
+		return
+	}
+
+	// 失败后重试
+	backend.SetError(errors.New("backend down"))
+	_ = cache.Set("d", "4")
+	clock.Advance(time.Second)
+	if !waitFor(func() bool { return atomic.LoadInt64(&backend.puts) == 2 && cache.behind.size() == 1 }) {
+		t.Fatal("failed key should retry")
+		return
+	}
+	backend.SetError(nil)
+
+	// Close 时写入
+	_ = cache.Set("e", "5")
+	cache.Close()
+	if backend.Len() != 4 {
+		t.Fatal("close should flush ", backend.Len())
+		return
+	}
+}
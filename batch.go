@@ -0,0 +1,213 @@
+package gocache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// BatchResult 批量加载中单个 key 的结果，Err 为 ErrNotFound 时按 Config.NegativeTTL 缓存不存在
+type BatchResult struct {
+	Value interface{}
+	TTL   time.Duration // 同 SetWithTTL，< 0 (NoExpiration) 永久有效，0 不写入缓存
+	Err   error
+}
+
+// BatchLoader 批量加载 keys，没有返回结果的 key 视为 ErrNotFound，返回 error 时所有 key 都返回该错误
+type BatchLoader func(ctx context.Context, keys []string) (map[string]BatchResult, error)
+
+// GetManyOrLoad 批量读取，未命中的 key 一次调用 batchLoader 加载并写入缓存
+// values 只包含存在的 key，errs 包含加载失败或不存在(ErrNotFound)的 key，写入缓存失败时 values、errs 中都有该 key
+// 需要合并多次调用的未命中 key 时使用 NewBatcher
+func (mem *MemCache) GetManyOrLoad(ctx context.Context, keys []string, batchLoader BatchLoader) (values map[string]interface{}, errs map[string]error) {
+	values, errs, misses := mem.lookupMany(keys)
+	if len(misses) == 0 {
+		return values, errs
+	}
+	results, err := batchLoader(ctx, misses)
+	mem.saveBatch(misses, results, err, values, errs)
+	return values, errs
+}
+
+// lookupMany 读取缓存，返回命中的值、不存在标记的 key 和去重后未命中的 key
+func (mem *MemCache) lookupMany(keys []string) (values map[string]interface{}, errs map[string]error, misses []string) {
+	values = make(map[string]interface{}, len(keys))
+	errs = make(map[string]error)
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		// 未命中时由 BatchLoader 加载，不经过 Backend 读穿透
+		if ev, ok := mem.getCached(key); ok {
+			values[key] = ev.Value
+			continue
+		}
+		if ev, ok := mem.peek(key); ok && ev.Tombstone {
+			errs[key] = ErrNotFound
+			continue
+		}
+		misses = append(misses, key)
+	}
+	return values, errs, misses
+}
+
+// saveBatch 写入批量加载的结果，并填充到 values、errs
+func (mem *MemCache) saveBatch(keys []string, results map[string]BatchResult, err error, values map[string]interface{}, errs map[string]error) {
+	for _, key := range keys {
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		r, ok := results[key]
+		if !ok || r.Err == ErrNotFound {
+			errs[key] = mem.setNotFound(key)
+			continue
+		}
+		if r.Err != nil {
+			errs[key] = r.Err
+			continue
+		}
+		values[key] = r.Value
+		if r.TTL == 0 {
+			continue
+		}
+		if err := mem.set(key, mem.ttlValue(key, r.Value, r.TTL)); err != nil {
+			errs[key] = err
+		}
+	}
+}
+
+// Batcher 合并一段时间内多次调用未命中的 key，一次调用 BatchLoader，类似 DataLoader
+type Batcher struct {
+	mem      *MemCache
+	loader   BatchLoader
+	wait     time.Duration
+	maxBatch int
+
+	mutex   sync.Mutex
+	pending *batch
+}
+
+// batch 等待加载的一批 key，done 关闭后 values、errs 可读
+type batch struct {
+	keys   []string
+	index  map[string]struct{}
+	done   chan struct{}
+	values map[string]interface{}
+	errs   map[string]error
+}
+
+// NewBatcher wait - 第一个未命中的 key 等待合并的时间，建议几毫秒，使用系统时间
+// maxBatch - 每批最多的 key 数量，达到后立即加载，<= 0 不限制
+// batchLoader 使用 context.Background() 调用，不受单次调用的 ctx 影响
+func (mem *MemCache) NewBatcher(batchLoader BatchLoader, wait time.Duration, maxBatch int) *Batcher {
+	return &Batcher{
+		mem:      mem,
+		loader:   batchLoader,
+		wait:     wait,
+		maxBatch: maxBatch,
+	}
+}
+
+// Get 读取单个 key，未命中时与其它调用合并加载
+func (b *Batcher) Get(ctx context.Context, key string) (interface{}, error) {
+	values, errs := b.GetMany(ctx, []string{key})
+	if err, ok := errs[key]; ok {
+		return values[key], err
+	}
+	return values[key], nil
+}
+
+// GetMany 同 GetManyOrLoad，未命中的 key 加入当前批次，等待加载完成或 ctx 结束
+func (b *Batcher) GetMany(ctx context.Context, keys []string) (values map[string]interface{}, errs map[string]error) {
+	values, errs, misses := b.mem.lookupMany(keys)
+	if len(misses) == 0 {
+		return values, errs
+	}
+
+	batches := make(map[*batch][]string)
+	for _, key := range misses {
+		bt := b.add(key)
+		batches[bt] = append(batches[bt], key)
+	}
+
+	for bt, keys := range batches {
+		select {
+		case <-bt.done:
+			for _, key := range keys {
+				if v, ok := bt.values[key]; ok {
+					values[key] = v
+				}
+				if err, ok := bt.errs[key]; ok {
+					errs[key] = err
+				}
+			}
+		case <-ctx.Done():
+			for _, key := range keys {
+				errs[key] = ctx.Err()
+			}
+		}
+	}
+	return values, errs
+}
+
+// add 加入当前批次，返回 key 所在的批次
+func (b *Batcher) add(key string) *batch {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.pending == nil {
+		b.pending = &batch{
+			index: make(map[string]struct{}),
+			done:  make(chan struct{}),
+		}
+		bt := b.pending
+		time.AfterFunc(b.wait, func() { b.dispatch(bt) })
+	}
+	bt := b.pending
+	if _, ok := bt.index[key]; !ok {
+		bt.index[key] = struct{}{}
+		bt.keys = append(bt.keys, key)
+	}
+	if b.maxBatch > 0 && len(bt.keys) >= b.maxBatch {
+		b.pending = nil
+		go b.dispatch(bt)
+	}
+	return bt
+}
+
+// dispatch 加载一个批次，达到 maxBatch 和等待时间到期都会调用，只执行一次
+// 在后台 goroutine 执行，batchLoader panic 时记录日志，未完成的 key 返回 errLoaderPanic
+func (b *Batcher) dispatch(bt *batch) {
+	b.mutex.Lock()
+	if b.pending == bt {
+		b.pending = nil
+	}
+	if bt.values != nil {
+		b.mutex.Unlock()
+		return
+	}
+	bt.values = make(map[string]interface{}, len(bt.keys))
+	bt.errs = make(map[string]error)
+	b.mutex.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Batcher: batch loader panic, %v\n", r)
+			for _, key := range bt.keys {
+				_, loaded := bt.values[key]
+				if _, failed := bt.errs[key]; !loaded && !failed {
+					bt.errs[key] = errLoaderPanic
+				}
+			}
+		}
+		close(bt.done)
+	}()
+
+	results, err := b.loader(context.Background(), bt.keys)
+	b.mem.saveBatch(bt.keys, results, err, bt.values, bt.errs)
+}
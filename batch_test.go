@@ -0,0 +1,161 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_GetManyOrLoad(t *testing.T) {
+	cache := NewRWMapCache()
+	_ = cache.Set("a", "cached")
+
+	errDB := errors.New("db error")
+	var loaded []string
+	loader := func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		loaded = append(loaded, keys...)
+		return map[string]BatchResult{
+			"b": {Value: "b", TTL: time.Minute},
+			"c": {Err: errDB},
+		}, nil
+	}
+
+	values, errs := cache.GetManyOrLoad(context.Background(), []string{"a", "b", "c", "d", "b"}, loader)
+	sort.Strings(loaded)
+	if fmt.Sprint(loaded) != "[b c d]" {
+		t.Fatal("loaded keys error ", loaded)
+		return
+	}
+	if len(values) != 2 || values["a"].(string) != "cached" || values["b"].(string) != "b" {
+		t.Fatal("values error ", values)
+		return
+	}
+	if len(errs) != 2 || errs["c"] != errDB || errs["d"] != ErrNotFound {
+		t.Fatal("errs error ", errs)
+		return
+	}
+	if ttl, ok := cache.TTL("b"); !ok || ttl <= 59*time.Second {
+		t.Fatal("loaded value should cached ", ttl)
+		return
+	}
+
+	// 整体失败
+	_, errs = cache.GetManyOrLoad(context.Background(), []string{"b", "e"}, func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		return nil, errDB
+	})
+	if len(errs) != 1 || errs["e"] != errDB {
+		t.Fatal("batch error ", errs)
+		return
+	}
+}
+
+func TestBatcher_Coalesce(t *testing.T) {
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, NegativeTTL: time.Minute})
+
+	mutex := sync.Mutex{}
+	calls := make([][]string, 0)
+	b := cache.NewBatcher(func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		mutex.Lock()
+		calls = append(calls, keys)
+		mutex.Unlock()
+		results := make(map[string]BatchResult)
+		for _, key := range keys {
+			if key != "missing" {
+				results[key] = BatchResult{Value: "v-" + key, TTL: NoExpiration}
+			}
+		}
+		return results, nil
+	}, 20*time.Millisecond, 0)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("%d", i%5)
+			v, err := b.Get(context.Background(), key)
+			if err != nil || v.(string) != "v-"+key {
+				errs <- fmt.Errorf("get %s error %v %v", key, v, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+		return
+	}
+	if len(calls) != 1 || len(calls[0]) != 5 {
+		t.Fatal("should coalesce into one call ", calls)
+		return
+	}
+
+	// 命中缓存和不存在的标记不再加载
+	if _, err := b.Get(context.Background(), "missing"); err != ErrNotFound {
+		t.Fatal("should ErrNotFound ", err)
+		return
+	}
+	values, errMap := b.GetMany(context.Background(), []string{"0", "1", "missing"})
+	if len(values) != 2 || errMap["missing"] != ErrNotFound || len(calls) != 2 {
+		t.Fatal("should not load again ", values, errMap, calls)
+		return
+	}
+}
+
+func TestBatcher_MaxBatch(t *testing.T) {
+	cache := NewSyncMapCache()
+
+	mutex := sync.Mutex{}
+	calls := 0
+	b := cache.NewBatcher(func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		mutex.Lock()
+		calls++
+		mutex.Unlock()
+		if len(keys) > 2 {
+			return nil, errors.New("batch too large")
+		}
+		results := make(map[string]BatchResult)
+		for _, key := range keys {
+			results[key] = BatchResult{Value: key, TTL: time.Minute}
+		}
+		return results, nil
+	}, 10*time.Millisecond, 2)
+
+	values, errs := b.GetMany(context.Background(), []string{"1", "2", "3", "4", "5"})
+	if len(values) != 5 || len(errs) != 0 || calls != 3 {
+		t.Fatal("max batch error ", values, errs, calls)
+		return
+	}
+
+	// 等待时 ctx 结束
+	slow := cache.NewBatcher(func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	}, time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.Get(ctx, "slow"); err != context.DeadlineExceeded {
+		t.Fatal("should canceled ", err)
+		return
+	}
+}
+
+func TestBatcher_Panic(t *testing.T) {
+	cache := NewSyncMapCache()
+	b := cache.NewBatcher(func(ctx context.Context, keys []string) (map[string]BatchResult, error) {
+		panic("batch loader panic")
+	}, time.Millisecond, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	values, errs := b.GetMany(ctx, []string{"1", "2"})
+	if len(values) != 0 || errs["1"] != errLoaderPanic || errs["2"] != errLoaderPanic {
+		t.Fatal("panic should return errLoaderPanic ", values, errs)
+		return
+	}
+}
@@ -0,0 +1,601 @@
+package gocache
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+)
+
+const (
+	// entry 头部: 4 字节 entry 总长度 + 8 字节 key 哈希 + 2 字节 key 长度
+	entryHeaderSize = 14
+	maxEntryKeySize = 1<<16 - 1
+	// 单个分片使用 uint32 记录偏移
+	maxShardBytes = 1<<32 - 1
+	// 单个分片最小字节数，capacity 过小时使用
+	minShardBytes = 1 << 10
+)
+
+var (
+	// entry 超过分片大小或 key 过长，ByteStore 无法保存
+	ErrEntryTooLarge = errors.New("entry too large")
+)
+
+// ByteStore 字节存储 值经过 Codec 编码后存储在预分配的 []byte 环形缓冲区，索引为 map[uint64]uint32
+// 缓冲区和索引都不包含指针，GC 不需要扫描每个 entry，适合存储百万级以上 key
+//
+// Notice:
+//  1. 分片写满后会覆盖最早写入的内容，MemCache 使用时被覆盖的 key 按淘汰处理(RemovalEvicted)
+//  2. 删除和覆盖写入只更新索引，旧内容占用的空间在被环形覆盖时回收
+//  3. 读取需要解码，性能低于 SyncMap、RWMap，Range 会解码所有内容
+//  4. 使用 GobCodec 时，自定义结构体需要先注册
+//  5. MemCache 写入时编码失败或 entry 超过分片大小返回错误(ErrEntryTooLarge)，不写入
+type ByteStore struct {
+	shards []*byteShard
+	mask   uint64
+	codec  Codec
+	// 环形覆盖 key 时的回调，在分片锁外调用
+	onEvict func(key string, value interface{})
+}
+
+// NewByteStore capacity 总字节数，平均分配给各个分片，每个分片至少 minShardBytes，codec 为 nil 时使用 GobCodec
+func NewByteStore(capacity int64, codec Codec) *ByteStore {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	shardBytes := capacity / defaultShards
+	if shardBytes < minShardBytes {
+		shardBytes = minShardBytes
+	}
+	if shardBytes > maxShardBytes {
+		shardBytes = maxShardBytes
+	}
+	s := ByteStore{
+		shards: make([]*byteShard, defaultShards),
+		mask:   defaultShards - 1,
+		codec:  codec,
+	}
+	for i := range s.shards {
+		s.shards[i] = newByteShard(int(shardBytes))
+	}
+	return &s
+}
+
+// hashKey FNV-1a 64 位哈希
+func hashKey(key string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+func (s *ByteStore) shard(hash uint64) *byteShard {
+	return s.shards[hash&s.mask]
+}
+
+// setOnEvict 设置后分片记录被环形覆盖的 key，写入解锁后通过 f 通知，MemCache 创建时调用
+func (s *ByteStore) setOnEvict(f func(key string, value interface{})) {
+	s.onEvict = f
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+		shard.track = f != nil
+		shard.mutex.Unlock()
+	}
+}
+
+// notifyEvicted 在锁外解码被覆盖的 key 并回调
+func (s *ByteStore) notifyEvicted(evicted []byteEntry) {
+	if s.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		if value, ok := s.decode(e.key, e.data); ok {
+			s.onEvict(e.key, value)
+		}
+	}
+}
+
+func (s *ByteStore) decode(key string, data []byte) (interface{}, bool) {
+	value, err := s.codec.Decode(data)
+	if err != nil {
+		log.Printf("ByteStore: decode key %s error %v\n", key, err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (s *ByteStore) encode(key string, value interface{}) ([]byte, bool) {
+	data, err := s.codec.Encode(value)
+	if err != nil {
+		log.Printf("ByteStore: encode key %s error %v\n", key, err)
+		return nil, false
+	}
+	return data, true
+}
+
+func (s *ByteStore) Load(key string) (value interface{}, ok bool) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.RLock()
+	data, ok := shard.get(hash, key)
+	if ok {
+		// 复制后解锁，避免解码时缓冲区被覆盖
+		data = append([]byte(nil), data...)
+	}
+	shard.mutex.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	return s.decode(key, data)
+}
+
+// Store 编码失败或 entry 超过分片大小时不写入，并删除旧值
+func (s *ByteStore) Store(key string, value interface{}) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+	data, ok := s.encode(key, value)
+
+	shard.mutex.Lock()
+	if !ok || !shard.set(hash, key, data) {
+		shard.del(hash, key)
+	}
+	evicted := shard.takeEvicted()
+	shard.mutex.Unlock()
+
+	s.notifyEvicted(evicted)
+}
+
+func (s *ByteStore) Delete(key string) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+	shard.del(hash, key)
+	shard.mutex.Unlock()
+}
+
+func (s *ByteStore) LoadOrStore(key string, value interface{}) (actual interface{}, loaded bool) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+	data, ok := s.encode(key, value)
+
+	shard.mutex.Lock()
+	old, loaded := shard.get(hash, key)
+	if loaded {
+		old = append([]byte(nil), old...)
+	} else if ok {
+		shard.set(hash, key, data)
+	}
+	evicted := shard.takeEvicted()
+	shard.mutex.Unlock()
+
+	s.notifyEvicted(evicted)
+
+	if !loaded {
+		return value, false
+	}
+	actual, _ = s.decode(key, old)
+	return actual, true
+}
+
+func (s *ByteStore) LoadAndDelete(key string) (value interface{}, loaded bool) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.Lock()
+	old, loaded := shard.get(hash, key)
+	if loaded {
+		old = append([]byte(nil), old...)
+		shard.del(hash, key)
+	}
+	shard.mutex.Unlock()
+
+	if !loaded {
+		return nil, false
+	}
+	value, _ = s.decode(key, old)
+	return value, true
+}
+
+// Swap 编码失败或 entry 超过分片大小时不写入，并删除旧值
+func (s *ByteStore) Swap(key string, value interface{}) (previous interface{}, loaded bool) {
+	previous, loaded, err := s.trySwap(key, value)
+	if err != nil {
+		log.Printf("ByteStore: swap key %s error %v\n", key, err)
+		return s.LoadAndDelete(key)
+	}
+	return previous, loaded
+}
+
+// trySwap 同 Swap，编码失败或 entry 超过分片大小时不写入，保留旧值并返回错误，MemCache 使用
+func (s *ByteStore) trySwap(key string, value interface{}) (previous interface{}, loaded bool, err error) {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+	data, err := s.codec.Encode(value)
+	if err != nil {
+		return nil, false, err
+	}
+	if !shard.fits(key, data) {
+		return nil, false, ErrEntryTooLarge
+	}
+
+	shard.mutex.Lock()
+	old, loaded := shard.get(hash, key)
+	if loaded {
+		old = append([]byte(nil), old...)
+	}
+	shard.set(hash, key, data)
+	evicted := shard.takeEvicted()
+	shard.mutex.Unlock()
+
+	s.notifyEvicted(evicted)
+	if !loaded {
+		return nil, false, nil
+	}
+	previous, _ = s.decode(key, old)
+	return previous, true, nil
+}
+
+func (s *ByteStore) Exists(key string) bool {
+	hash := hashKey(key)
+	shard := s.shard(hash)
+
+	shard.mutex.RLock()
+	_, ok := shard.get(hash, key)
+	shard.mutex.RUnlock()
+	return ok
+}
+
+// Range 遍历时持有分片读锁，并逐个解码
+func (s *ByteStore) Range(f func(k string, v interface{}) bool) {
+	for _, shard := range s.shards {
+		next := true
+		shard.mutex.RLock()
+		for _, offset := range shard.index {
+			key, data := shard.entry(offset)
+			value, ok := s.decode(key, data)
+			if !ok {
+				continue
+			}
+			if next = f(key, value); !next {
+				break
+			}
+		}
+		shard.mutex.RUnlock()
+		if !next {
+			return
+		}
+	}
+}
+
+func (s *ByteStore) Flush() {
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+		shard.reset()
+		shard.mutex.Unlock()
+	}
+}
+
+func (s *ByteStore) Size() int64 {
+	size := int64(0)
+	for _, shard := range s.shards {
+		shard.mutex.RLock()
+		size += int64(len(shard.index))
+		shard.mutex.RUnlock()
+	}
+	return size
+}
+
+// byteGroup 同一个分片中的 key 和哈希
+type byteGroup struct {
+	keys   []string
+	hashes []uint64
+	data   [][]byte
+}
+
+// group 按分片分组，每个分片只加一次锁
+func (s *ByteStore) group(keys []string) map[*byteShard]*byteGroup {
+	groups := make(map[*byteShard]*byteGroup)
+	for _, key := range keys {
+		hash := hashKey(key)
+		shard := s.shard(hash)
+		g, ok := groups[shard]
+		if !ok {
+			g = &byteGroup{}
+			groups[shard] = g
+		}
+		g.keys = append(g.keys, key)
+		g.hashes = append(g.hashes, hash)
+	}
+	return groups
+}
+
+func (s *ByteStore) MLoad(keys []string) map[string]interface{} {
+	raw := make(map[string][]byte, len(keys))
+	for shard, g := range s.group(keys) {
+		shard.mutex.RLock()
+		for i, key := range g.keys {
+			if data, ok := shard.get(g.hashes[i], key); ok {
+				raw[key] = append([]byte(nil), data...)
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return s.decodeAll(raw)
+}
+
+// MStore 编码在加锁之前完成，编码失败或 entry 超过分片大小的 key 不写入，并删除旧值
+func (s *ByteStore) MStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool) {
+	groups := s.group(valueKeys(values))
+	for _, g := range groups {
+		g.data = make([][]byte, len(g.keys))
+		for i, key := range g.keys {
+			if data, ok := s.encode(key, values[key]); ok {
+				g.data[i] = data
+			}
+		}
+	}
+	return s.mstore(groups, limit)
+}
+
+// tryMStore 同 MStore，任意一个 key 编码失败或 entry 超过分片大小时全部不写入并返回错误，MemCache 使用
+func (s *ByteStore) tryMStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool, err error) {
+	groups := s.group(valueKeys(values))
+	for shard, g := range groups {
+		g.data = make([][]byte, len(g.keys))
+		for i, key := range g.keys {
+			data, err := s.codec.Encode(values[key])
+			if err != nil {
+				return nil, false, err
+			}
+			if !shard.fits(key, data) {
+				return nil, false, ErrEntryTooLarge
+			}
+			g.data[i] = data
+		}
+	}
+	previous, ok = s.mstore(groups, limit)
+	return previous, ok, nil
+}
+
+// mstore limit >= 0 时按顺序锁住所有分片，保证 limit 检查和写入是原子的
+func (s *ByteStore) mstore(groups map[*byteShard]*byteGroup, limit int64) (previous map[string]interface{}, ok bool) {
+	raw := make(map[string][]byte)
+	var evicted []byteEntry
+	if limit < 0 {
+		for shard, g := range groups {
+			shard.mutex.Lock()
+			shard.mset(g, raw)
+			evicted = append(evicted, shard.takeEvicted()...)
+			shard.mutex.Unlock()
+		}
+		s.notifyEvicted(evicted)
+		return s.decodeAll(raw), true
+	}
+
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+	}
+	size := int64(0)
+	for _, shard := range s.shards {
+		size += int64(len(shard.index))
+	}
+	for shard, g := range groups {
+		for i, key := range g.keys {
+			if _, ok := shard.get(g.hashes[i], key); !ok {
+				size++
+			}
+		}
+	}
+	if size <= limit {
+		for shard, g := range groups {
+			shard.mset(g, raw)
+			evicted = append(evicted, shard.takeEvicted()...)
+		}
+	}
+	for _, shard := range s.shards {
+		shard.mutex.Unlock()
+	}
+	if size > limit {
+		return nil, false
+	}
+	s.notifyEvicted(evicted)
+	return s.decodeAll(raw), true
+}
+
+// valueKeys 批量写入的 key
+func valueKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *ByteStore) MDelete(keys []string) map[string]interface{} {
+	raw := make(map[string][]byte, len(keys))
+	for shard, g := range s.group(keys) {
+		shard.mutex.Lock()
+		for i, key := range g.keys {
+			if data, ok := shard.get(g.hashes[i], key); ok {
+				raw[key] = append([]byte(nil), data...)
+				shard.del(g.hashes[i], key)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+	return s.decodeAll(raw)
+}
+
+// decodeAll 在锁外解码
+func (s *ByteStore) decodeAll(raw map[string][]byte) map[string]interface{} {
+	values := make(map[string]interface{}, len(raw))
+	for key, data := range raw {
+		if value, ok := s.decode(key, data); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// mset 写入一组 key，旧值复制到 previous，需要持有写锁
+func (s *byteShard) mset(g *byteGroup, previous map[string][]byte) {
+	for i, key := range g.keys {
+		hash := g.hashes[i]
+		if old, ok := s.get(hash, key); ok {
+			previous[key] = append([]byte(nil), old...)
+		}
+		if g.data[i] == nil || !s.set(hash, key, g.data[i]) {
+			s.del(hash, key)
+		}
+	}
+}
+
+// byteShard 环形缓冲区，entry 按写入顺序存放在 [head, tail)，回绕后存放在 [head, end) 和 [0, tail)
+type byteShard struct {
+	mutex sync.RWMutex
+	index map[uint64]uint32
+	buf   []byte
+	head  int
+	tail  int
+	// 回绕前数据的结束位置
+	end int
+	// 缓冲区中的 entry 数量，包含已删除和被覆盖的
+	count int
+	// 是否记录被覆盖的 key
+	track   bool
+	evicted []byteEntry
+}
+
+// byteEntry 被环形覆盖的 key 和编码后的值
+type byteEntry struct {
+	key  string
+	data []byte
+}
+
+func newByteShard(size int) *byteShard {
+	s := byteShard{
+		index: make(map[uint64]uint32),
+		buf:   make([]byte, size),
+		end:   size,
+	}
+	return &s
+}
+
+func (s *byteShard) entry(offset uint32) (key string, data []byte) {
+	e := s.buf[offset:]
+	n := binary.LittleEndian.Uint32(e)
+	keyLen := int(binary.LittleEndian.Uint16(e[12:]))
+	key = string(e[entryHeaderSize : entryHeaderSize+keyLen])
+	return key, e[entryHeaderSize+keyLen : n]
+}
+
+// get 哈希冲突时比较 key，返回的 data 引用缓冲区
+func (s *byteShard) get(hash uint64, key string) ([]byte, bool) {
+	offset, ok := s.index[hash]
+	if !ok {
+		return nil, false
+	}
+	k, data := s.entry(offset)
+	if k != key {
+		return nil, false
+	}
+	return data, true
+}
+
+// fits entry 能否写入分片
+func (s *byteShard) fits(key string, data []byte) bool {
+	return len(key) <= maxEntryKeySize && entryHeaderSize+len(key)+len(data) <= len(s.buf)
+}
+
+func (s *byteShard) set(hash uint64, key string, data []byte) bool {
+	if !s.fits(key, data) {
+		return false
+	}
+	n := entryHeaderSize + len(key) + len(data)
+
+	for {
+		if s.count == 0 {
+			s.head, s.tail, s.end = 0, 0, len(s.buf)
+		}
+		if s.tail > s.head || s.count == 0 {
+			// 未回绕，尾部空间不足时回绕到头部
+			if len(s.buf)-s.tail >= n {
+				break
+			}
+			s.end = s.tail
+			s.tail = 0
+			continue
+		}
+		// 已回绕，空闲空间为 [tail, head)，不足时覆盖最早写入的 entry
+		if s.head-s.tail >= n {
+			break
+		}
+		s.pop(key)
+	}
+	// 哈希冲突的其他 key 会被覆盖
+	if offset, ok := s.index[hash]; ok && s.track {
+		if k, old := s.entry(offset); k != key {
+			s.evicted = append(s.evicted, byteEntry{key: k, data: append([]byte(nil), old...)})
+		}
+	}
+
+	e := s.buf[s.tail:]
+	binary.LittleEndian.PutUint32(e, uint32(n))
+	binary.LittleEndian.PutUint64(e[4:], hash)
+	binary.LittleEndian.PutUint16(e[12:], uint16(len(key)))
+	copy(e[entryHeaderSize:], key)
+	copy(e[entryHeaderSize+len(key):], data)
+
+	s.index[hash] = uint32(s.tail)
+	s.tail += n
+	s.count++
+	return true
+}
+
+func (s *byteShard) del(hash uint64, key string) {
+	if _, ok := s.get(hash, key); ok {
+		delete(s.index, hash)
+	}
+}
+
+// pop 移除最早写入的 entry，如果索引仍指向它，同时删除索引，
+// 正在写入的 key 由调用方处理旧值，其他 key 记录为被覆盖
+func (s *byteShard) pop(writing string) {
+	e := s.buf[s.head:]
+	n := binary.LittleEndian.Uint32(e)
+	hash := binary.LittleEndian.Uint64(e[4:])
+	if offset, ok := s.index[hash]; ok && offset == uint32(s.head) {
+		if s.track {
+			if k, data := s.entry(offset); k != writing {
+				s.evicted = append(s.evicted, byteEntry{key: k, data: append([]byte(nil), data...)})
+			}
+		}
+		delete(s.index, hash)
+	}
+
+	s.head += int(n)
+	s.count--
+	if s.head >= s.end {
+		s.head = 0
+		s.end = len(s.buf)
+	}
+}
+
+// takeEvicted 取出记录的被覆盖 key，需要持有写锁
+func (s *byteShard) takeEvicted() []byteEntry {
+	evicted := s.evicted
+	s.evicted = nil
+	return evicted
+}
+
+func (s *byteShard) reset() {
+	s.index = make(map[uint64]uint32)
+	s.head, s.tail, s.end, s.count = 0, 0, len(s.buf), 0
+	s.evicted = nil
+}
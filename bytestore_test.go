@@ -0,0 +1,189 @@
+package gocache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestByteStore(t *testing.T) {
+	bs := NewByteStore(1<<20, nil)
+
+	bs.Store("store", "1")
+	v, ok := bs.Load("store")
+	if !ok || v.(string) != "1" {
+		t.Fatal("load error ", v)
+		return
+	}
+
+	ac, loaded := bs.LoadOrStore("store", "2")
+	if !loaded || ac.(string) != "1" {
+		t.Fatal("load or store error ", ac)
+		return
+	}
+	prev, loaded := bs.Swap("store", "3")
+	if !loaded || prev.(string) != "1" {
+		t.Fatal("swap error ", prev)
+		return
+	}
+	v, loaded = bs.LoadAndDelete("store")
+	if !loaded || v.(string) != "3" {
+		t.Fatal("load and delete error ", v)
+		return
+	}
+	if bs.Exists("store") || bs.Size() != 0 {
+		t.Fatal("store should deleted")
+		return
+	}
+
+	for i := 0; i < 100; i++ {
+		bs.Store(fmt.Sprintf("%d", i), i)
+	}
+	count := 0
+	bs.Range(func(k string, v interface{}) bool {
+		if k != fmt.Sprintf("%d", v.(int)) {
+			t.Fatal("range value error ", k, v)
+		}
+		count++
+		return true
+	})
+	if count != 100 || bs.Size() != 100 {
+		t.Fatal("range error ", count, bs.Size())
+		return
+	}
+
+	bs.Flush()
+	if bs.Size() != 0 {
+		t.Fatal("flush error ", bs.Size())
+		return
+	}
+}
+
+func TestByteShard_Ring(t *testing.T) {
+	shard := newByteShard(100)
+	data := make([]byte, 16)
+	// 每个 entry 占 14 + 2 + 16 = 32 字节，最多同时保存 3 个
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("%02d", i)
+		if !shard.set(hashKey(key), key, data) {
+			t.Fatal("set error ", key)
+			return
+		}
+	}
+	if len(shard.index) != 3 {
+		t.Fatal("ring size error ", len(shard.index))
+		return
+	}
+	for i := 7; i < 10; i++ {
+		key := fmt.Sprintf("%02d", i)
+		if _, ok := shard.get(hashKey(key), key); !ok {
+			t.Fatal("newest key should exists ", key)
+			return
+		}
+	}
+
+	if shard.set(hashKey("big"), "big", make([]byte, 100)) {
+		t.Fatal("entry over shard size should not set")
+		return
+	}
+}
+
+func TestMemCacheImpl_ByteStore(t *testing.T) {
+	cache := NewByteStoreCache(1 << 20)
+	cache.GobRegister(iType{})
+
+	if err := cache.Set("iType", iType{Value: "1"}); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if err := cache.SetWithExpire("string", "2", 10); err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	v, ok := cache.Get("iType")
+	if !ok || v.(iType).Value != "1" {
+		t.Fatal("get iType error ", v)
+		return
+	}
+	_, ttl, ok := cache.GetWithExpire("string")
+	if !ok || ttl < 9 {
+		t.Fatal("get string error ", ttl)
+		return
+	}
+	if cache.Keys("").Size() != 2 {
+		t.Fatal("keys error ", cache.Keys("").Size())
+		return
+	}
+}
+
+func TestMemCacheImpl_ByteStoreRingEvict(t *testing.T) {
+	cache := NewByteStoreCacheWithConfig(32*256, Config{LimitSize: -1, Evict: EvictLRU})
+	n := 2000
+	for i := 0; i < n; i++ {
+		_ = cache.Set(fmt.Sprintf("%d", i), i)
+	}
+	stats := cache.Stats()
+	if stats.Size >= int64(n) || stats.Cost != stats.Size {
+		t.Fatal("ring evict cost error ", stats.Size, stats.Cost)
+		return
+	}
+	if stats.Evictions != int64(n)-stats.Size {
+		t.Fatal("ring evict evictions error ", stats.Evictions, stats.Size)
+		return
+	}
+}
+
+func TestByteStore_MinCapacity(t *testing.T) {
+	bs := NewByteStore(0, nil)
+	bs.Store("store", "1")
+	if v, ok := bs.Load("store"); !ok || v.(string) != "1" {
+		t.Fatal("min capacity store error ", v)
+		return
+	}
+}
+
+// unregisteredType 不注册，gob 编码失败
+type unregisteredType struct {
+	Value string
+}
+
+func TestMemCacheImpl_ByteStoreSetError(t *testing.T) {
+	cache := NewByteStoreCacheWithConfig(1<<20, Config{LimitSize: -1, Evict: EvictLRU})
+	_ = cache.Set("x", "1")
+
+	if err := cache.Set("x", unregisteredType{Value: "2"}); err == nil {
+		t.Fatal("unregistered type should set error")
+		return
+	}
+	if err := cache.Set("big", make([]byte, 100<<10)); err != ErrEntryTooLarge {
+		t.Fatal("oversized entry should ErrEntryTooLarge ", err)
+		return
+	}
+	if err := cache.MSet(map[string]interface{}{"a": "1", "big": make([]byte, 100<<10)}, time.Minute); err != ErrEntryTooLarge {
+		t.Fatal("mset oversized entry should ErrEntryTooLarge ", err)
+		return
+	}
+
+	// 写入失败保留旧值，不计数
+	if v, ok := cache.Get("x"); !ok || v.(string) != "1" {
+		t.Fatal("failed set should keep old value ", v)
+		return
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("failed mset should not write")
+		return
+	}
+	if cache.Size() != 1 || cache.Cost() != 1 {
+		t.Fatal("failed set size or cost error ", cache.Size(), cache.Cost())
+		return
+	}
+	if key, ok := cache.policy.Evict(); !ok || key != "x" {
+		t.Fatal("failed set should not insert policy ", key)
+		return
+	}
+	if _, ok := cache.policy.Evict(); ok {
+		t.Fatal("failed set should not insert policy")
+		return
+	}
+}
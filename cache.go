@@ -1,18 +1,30 @@
 package gocache
 
+import "time"
+
 type Cache interface {
-	Get(key string) (value interface{}, exists bool)                      //
-	GetWithExpire(key string) (value interface{}, ttl int64, exists bool) // 返回值和剩余时间
-	Set(key string, value interface{}) error                              //
-	SetWithExpire(key string, value interface{}, ttl int64) error         // ttl 秒级别
-	Keys(prefix string) Keys                                              // prefix - 前缀查询，"" 查询所有， 只返回当前有效的key
-	Delete(key string)                                                    //
-	Size() int64                                                          // 当前存储的数据量
-	FlushAll()                                                            // 删除所有 key
-	GobRegister(v ...interface{})                                         // 注册自定义结构体
-	WriteToDisk() error                                                   // 写入数据到磁盘， 如果存在自定义结构类型，在使用时 一定要先注册结构
-	LoadFromDisk() error                                                  // 从磁盘加载数据
-	Close()                                                               //
+	Get(key string) (value interface{}, exists bool)                           //
+	GetWithExpire(key string) (value interface{}, ttl int64, exists bool)      // 返回值和剩余时间
+	Set(key string, value interface{}) error                                   //
+	SetWithExpire(key string, value interface{}, ttl int64) error              // ttl 秒级别
+	GetWithTTL(key string) (value interface{}, ttl time.Duration, exists bool) // 返回值和剩余时间，纳秒精度
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error         // ttl 纳秒精度，< 0 永久有效
+	Expire(key string, ttl time.Duration) bool                                 // 修改过期时间，不改写值，ttl <= 0 立即过期
+	ExpireAt(key string, at time.Time) bool                                    // 修改过期时间为 at
+	Persist(key string) bool                                                   // 移除过期时间，永久有效
+	TTL(key string) (ttl time.Duration, exists bool)                           // 剩余有效时间，永久有效为 -1
+	Touch(key string) bool                                                     // 访问 key，延长滑动过期时间
+	MGet(keys []string) map[string]interface{}                                 // 批量读取，只返回存在的 key
+	MSet(values map[string]interface{}, ttl time.Duration) error               // 批量写入，容量不足时全部不写入
+	MDelete(keys []string)                                                     // 批量删除
+	Keys(prefix string) Keys                                                   // prefix - 前缀查询，"" 查询所有， 只返回当前有效的key
+	Delete(key string)                                                         //
+	Size() int64                                                               // 当前存储的数据量
+	FlushAll()                                                                 // 删除所有 key
+	GobRegister(v ...interface{})                                              // 注册自定义结构体
+	WriteToDisk() error                                                        // 写入数据到磁盘， 如果存在自定义结构类型，在使用时 一定要先注册结构
+	LoadFromDisk() error                                                       // 从磁盘加载数据
+	Close()                                                                    //
 }
 
 type Keys interface {
@@ -29,4 +41,14 @@ type Store interface {
 	Range(f func(k string, v interface{}) bool)
 	Size() int64
 	Flush()
+	MLoad(keys []string) map[string]interface{}                                                   // 批量读取，不返回不存在的 key
+	MStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool) // 批量写入并返回被覆盖的值，limit >= 0 时写入后数量超过 limit 则全部不写入，返回 false
+	MDelete(keys []string) map[string]interface{}                                                 // 批量删除，返回删除前的值
+}
+
+// SwapStore 可选接口，Store 实现后写入和删除一次拿到旧值，
+// 未实现时 MemCache 在 key 锁内使用 LoadOrStore+Store、Load+Delete 代替
+type SwapStore interface {
+	LoadAndDelete(key string) (value interface{}, loaded bool)              // 删除并返回删除前的值
+	Swap(key string, value interface{}) (previous interface{}, loaded bool) // 写入并返回写入前的值
 }
@@ -0,0 +1,90 @@
+package gocache
+
+// RemovalReason key 被移除的原因
+type RemovalReason int
+
+const (
+	// RemovalExpired 过期，读取时发现或被自动清理
+	RemovalExpired RemovalReason = iota + 1
+	// RemovalEvicted 超过容量被淘汰
+	RemovalEvicted
+	// RemovalDeleted 调用 Delete 删除
+	RemovalDeleted
+	// RemovalFlushed 调用 FlushAll 清空
+	RemovalFlushed
+	// RemovalReplaced 被重新写入的值覆盖，回调的 value 为旧值
+	RemovalReplaced
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case RemovalExpired:
+		return "expired"
+	case RemovalEvicted:
+		return "evicted"
+	case RemovalDeleted:
+		return "deleted"
+	case RemovalFlushed:
+		return "flushed"
+	case RemovalReplaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+// RemovalFunc key 被移除时的回调
+type RemovalFunc func(key string, value interface{}, reason RemovalReason)
+
+// OnRemoved 注册回调，任何原因移除 key 时触发
+// 回调在 Store 锁之外同步执行，可以在回调中再次操作缓存，耗时操作建议异步处理
+func (mem *MemCache) OnRemoved(fn RemovalFunc) {
+	if fn == nil {
+		return
+	}
+	mem.callbackMutex.Lock()
+	mem.callbacks = append(mem.callbacks, fn)
+	mem.callbackMutex.Unlock()
+}
+
+// OnEvicted 注册回调，超过容量淘汰 key 时触发
+func (mem *MemCache) OnEvicted(fn func(key string, value interface{})) {
+	mem.onReason(RemovalEvicted, fn)
+}
+
+// OnExpired 注册回调，过期 key 被删除时触发
+func (mem *MemCache) OnExpired(fn func(key string, value interface{})) {
+	mem.onReason(RemovalExpired, fn)
+}
+
+// OnDeleted 注册回调，调用 Delete 删除 key 时触发
+func (mem *MemCache) OnDeleted(fn func(key string, value interface{})) {
+	mem.onReason(RemovalDeleted, fn)
+}
+
+func (mem *MemCache) onReason(reason RemovalReason, fn func(key string, value interface{})) {
+	if fn == nil {
+		return
+	}
+	mem.OnRemoved(func(key string, value interface{}, r RemovalReason) {
+		if r == reason {
+			fn(key, value)
+		}
+	})
+}
+
+func (mem *MemCache) hasCallbacks() bool {
+	mem.callbackMutex.RLock()
+	ok := len(mem.callbacks) > 0
+	mem.callbackMutex.RUnlock()
+	return ok
+}
+
+func (mem *MemCache) notify(key string, value interface{}, reason RemovalReason) {
+	mem.callbackMutex.RLock()
+	callbacks := mem.callbacks
+	mem.callbackMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(key, value, reason)
+	}
+}
@@ -0,0 +1,120 @@
+package gocache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源，测试时可以使用 FakeClock 控制时间，不需要 time.Sleep
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 与 time.Ticker 一致，接收方处理不及时会丢弃 tick
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// realClock 系统时间，默认
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) Chan() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// FakeClock 手动推进的时间，Advance 时触发到期的 Ticker
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTicker d <= 0 时 panic，与 time.NewTicker 一致
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &fakeTicker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance 时间前进 d，到期的 Ticker 各触发一次，跨过多个周期时与 time.Ticker 一样只保留一个 tick
+// tick 由后台 goroutine 异步处理，Advance 返回时处理不一定完成
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for i, v := range t.clock.tickers {
+		if v == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gocache
+
+import (
+	"testing"
+	"time"
+)
+
+// waitFor 等待后台 goroutine 处理 FakeClock 触发的 tick，最多 1 秒
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewFakeClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+	if !clock.Now().Equal(start.Add(500 * time.Millisecond)) {
+		t.Fatal("now error ", clock.Now())
+		return
+	}
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticker should not fire")
+		return
+	default:
+	}
+
+	// 跨过多个周期只触发一次
+	clock.Advance(3 * time.Second)
+	select {
+	case tick := <-ticker.Chan():
+		if !tick.Equal(start.Add(3500 * time.Millisecond)) {
+			t.Fatal("tick time error ", tick)
+			return
+		}
+	default:
+		t.Fatal("ticker should fire")
+		return
+	}
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticker should fire once")
+		return
+	default:
+	}
+
+	// 下一次在 4s
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.Chan():
+	default:
+		t.Fatal("ticker should fire at next period")
+		return
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case <-ticker.Chan():
+		t.Fatal("stopped ticker should not fire")
+		return
+	default:
+	}
+}
+
+func TestMemCacheImpl_FakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
+
+	_ = cache.SetWithTTL("key", "1", time.Hour)
+	clock.Advance(30 * time.Minute)
+	if ttl, ok := cache.TTL("key"); !ok || ttl != 30*time.Minute {
+		t.Fatal("ttl error ", ttl)
+		return
+	}
+	clock.Advance(30 * time.Minute)
+	if _, ok := cache.Get("key"); ok {
+		t.Fatal("key should expired")
+		return
+	}
+}
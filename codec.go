@@ -0,0 +1,37 @@
+package gocache
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+func init() {
+	// ByteStore 通过 Codec 编码 MemCache 存储的 expireValue
+	gob.Register(expireValue{})
+}
+
+// Codec 值编解码，ByteStore 使用 Codec 把值序列化后存储
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// GobCodec gob 编解码，自定义结构体需要先 gob.Register 或 MemCache.GobRegister
+type GobCodec struct{}
+
+func (GobCodec) Encode(value interface{}) ([]byte, error) {
+	data := bytes.Buffer{}
+	// 以接口类型编码，解码时才能还原具体类型
+	if err := gob.NewEncoder(&data).Encode(&value); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
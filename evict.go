@@ -0,0 +1,157 @@
+package gocache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy 淘汰策略
+// MemCache 在读取命中、写入新 key、删除 key 时通知策略，超过容量时由 Evict 选出被淘汰的 key
+// 实现需要并发安全，且一个实例只能给一个 MemCache 使用
+type EvictionPolicy interface {
+	Access(key string)            // 读取命中或覆盖写入，key 不存在时忽略
+	Insert(key string)            // 写入新 key
+	Delete(key string)            // key 被删除
+	Evict() (key string, ok bool) // 选出并移除一个被淘汰的 key，ok = false 无可淘汰
+	Flush()                       // 清空
+}
+
+// newEvictionPolicy 根据配置创建淘汰策略，nil 不淘汰
+func newEvictionPolicy(config Config) EvictionPolicy {
+	if config.EvictionPolicy != nil {
+		return config.EvictionPolicy
+	}
+	switch config.Evict {
+	case EvictLRU:
+		return NewLRUPolicy()
+	case EvictLFU:
+		return NewLFUPolicy()
+	case EvictFIFO:
+		return NewFIFOPolicy()
+	case EvictRandom:
+		return NewRandomPolicy(defaultRandomSamples)
+	case EvictTinyLFU:
+		return NewTinyLFUPolicy(int(config.LimitSize))
+	}
+	return nil
+}
+
+// LRUPolicy 最近最少使用淘汰，链表头部为最近访问的 key
+type LRUPolicy struct {
+	mutex sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func NewLRUPolicy() *LRUPolicy {
+	l := LRUPolicy{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+	return &l
+}
+
+func (l *LRUPolicy) Access(key string) {
+	l.mutex.Lock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+	}
+	l.mutex.Unlock()
+}
+
+func (l *LRUPolicy) Insert(key string) {
+	l.mutex.Lock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+	} else {
+		l.items[key] = l.ll.PushFront(key)
+	}
+	l.mutex.Unlock()
+}
+
+func (l *LRUPolicy) Delete(key string) {
+	l.mutex.Lock()
+	if e, ok := l.items[key]; ok {
+		l.ll.Remove(e)
+		delete(l.items, key)
+	}
+	l.mutex.Unlock()
+}
+
+// Evict 弹出最久未访问的 key
+func (l *LRUPolicy) Evict() (key string, ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	e := l.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	l.ll.Remove(e)
+	key = e.Value.(string)
+	delete(l.items, key)
+	return key, true
+}
+
+func (l *LRUPolicy) Flush() {
+	l.mutex.Lock()
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+	l.mutex.Unlock()
+}
+
+// FIFOPolicy 先进先出淘汰，访问不影响顺序
+type FIFOPolicy struct {
+	mutex sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func NewFIFOPolicy() *FIFOPolicy {
+	f := FIFOPolicy{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+	return &f
+}
+
+func (f *FIFOPolicy) Access(key string) {}
+
+func (f *FIFOPolicy) Insert(key string) {
+	f.mutex.Lock()
+	if _, ok := f.items[key]; !ok {
+		f.items[key] = f.ll.PushFront(key)
+	}
+	f.mutex.Unlock()
+}
+
+func (f *FIFOPolicy) Delete(key string) {
+	f.mutex.Lock()
+	if e, ok := f.items[key]; ok {
+		f.ll.Remove(e)
+		delete(f.items, key)
+	}
+	f.mutex.Unlock()
+}
+
+// Evict 弹出最早写入的 key
+func (f *FIFOPolicy) Evict() (key string, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	e := f.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	f.ll.Remove(e)
+	key = e.Value.(string)
+	delete(f.items, key)
+	return key, true
+}
+
+func (f *FIFOPolicy) Flush() {
+	f.mutex.Lock()
+	f.ll.Init()
+	f.items = make(map[string]*list.Element)
+	f.mutex.Unlock()
+}
@@ -0,0 +1,216 @@
+package gocache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy()
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+	p.Access("a")
+	p.Delete("b")
+
+	for _, want := range []string{"c", "a"} {
+		key, ok := p.Evict()
+		if !ok || key != want {
+			t.Fatal("lru evict error ", key, want)
+			return
+		}
+	}
+	if _, ok := p.Evict(); ok {
+		t.Fatal("lru should empty")
+		return
+	}
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	p := NewFIFOPolicy()
+	p.Insert("a")
+	p.Insert("b")
+	p.Access("a")
+
+	key, ok := p.Evict()
+	if !ok || key != "a" {
+		t.Fatal("fifo evict error ", key)
+		return
+	}
+}
+
+func TestLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy()
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+	p.Access("a")
+	p.Access("a")
+	p.Access("c")
+
+	for _, want := range []string{"b", "c", "a"} {
+		key, ok := p.Evict()
+		if !ok || key != want {
+			t.Fatal("lfu evict error ", key, want)
+			return
+		}
+	}
+
+	// 老化后，过去的热点 key 计数减半
+	p.Insert("hot")
+	for i := 0; i < lfuAgingMin; i++ {
+		p.Access("hot")
+	}
+	if p.items["hot"].freq >= lfuAgingMin {
+		t.Fatal("lfu aging error ", p.items["hot"].freq)
+		return
+	}
+}
+
+func TestLFUPolicy_AgingOrder(t *testing.T) {
+	p := NewLFUPolicy()
+	p.Insert("p")
+	p.Insert("c")
+	p.Access("c")
+	p.Access("c")
+	p.Access("p")
+	// c 计数 3，p 计数 2，c 的访问时间更早
+	// 老化后都为 2，计数相同时淘汰最久未访问的 c
+	p.accesses = lfuAgingMin - 1
+	p.aging()
+
+	for _, want := range []string{"c", "p"} {
+		key, ok := p.Evict()
+		if !ok || key != want {
+			t.Fatal("lfu evict after aging error ", key, want)
+			return
+		}
+	}
+}
+
+func TestRandomPolicy(t *testing.T) {
+	p := NewRandomPolicy(defaultRandomSamples)
+	for i := 0; i < 100; i++ {
+		p.Insert(fmt.Sprintf("%d", i))
+	}
+	p.Delete("0")
+
+	evicted := make(map[string]bool)
+	for i := 0; i < 99; i++ {
+		key, ok := p.Evict()
+		if !ok || key == "0" || evicted[key] {
+			t.Fatal("random evict error ", key)
+			return
+		}
+		evicted[key] = true
+	}
+	if _, ok := p.Evict(); ok {
+		t.Fatal("random should empty")
+		return
+	}
+}
+
+func TestMemCacheImpl_EvictionPolicy(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize: 2,
+		Evict:     EvictLFU,
+	})
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+	cache.Get("a")
+	_ = cache.Set("c", 3)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("b should evicted")
+		return
+	}
+
+	cache = NewSyncMapCacheWithConfig(Config{
+		LimitSize:      2,
+		EvictionPolicy: NewFIFOPolicy(),
+	})
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+	cache.Get("a")
+	_ = cache.Set("c", 3)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("a should evicted")
+		return
+	}
+}
+
+func TestTinyLFUPolicy(t *testing.T) {
+	// 窗口容量 1，主空间容量 2
+	p := NewTinyLFUPolicy(3)
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+	p.Access("a")
+	p.Access("a")
+	p.Insert("d")
+
+	// 窗口候选 c 与试用区 b 频率相同，新来的 c 被淘汰
+	key, ok := p.Evict()
+	if !ok || key != "c" {
+		t.Fatal("tinylfu evict error ", key)
+		return
+	}
+
+	p.Access("d")
+	p.Access("d")
+	p.Insert("e")
+	// 窗口候选 d 频率高于试用区 b，b 被淘汰
+	key, ok = p.Evict()
+	if !ok || key != "b" {
+		t.Fatal("tinylfu evict error ", key)
+		return
+	}
+
+	p.Delete("e")
+	for _, want := range []string{"d", "a"} {
+		key, ok = p.Evict()
+		if !ok || key != want {
+			t.Fatal("tinylfu evict error ", key, want)
+			return
+		}
+	}
+	if _, ok := p.Evict(); ok {
+		t.Fatal("tinylfu should empty")
+		return
+	}
+}
+
+func TestMemCacheImpl_EvictTinyLFU(t *testing.T) {
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize: 100,
+		Evict:     EvictTinyLFU,
+	})
+	for i := 0; i < 50; i++ {
+		_ = cache.Set(fmt.Sprintf("hot-%d", i), i)
+	}
+	for n := 0; n < 5; n++ {
+		for i := 0; i < 50; i++ {
+			cache.Get(fmt.Sprintf("hot-%d", i))
+		}
+	}
+	// 批量扫描只出现一次的 key
+	for i := 0; i < 1000; i++ {
+		_ = cache.Set(fmt.Sprintf("scan-%d", i), i)
+	}
+
+	if cache.Size() > 100 {
+		t.Fatal("size over limit ", cache.Size())
+		return
+	}
+	hits := 0
+	for i := 0; i < 50; i++ {
+		if _, ok := cache.Get(fmt.Sprintf("hot-%d", i)); ok {
+			hits++
+		}
+	}
+	if hits < 45 {
+		t.Fatal("hot keys should retained ", hits)
+		return
+	}
+}
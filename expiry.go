@@ -0,0 +1,104 @@
+package gocache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// 过期索引桶的时间跨度
+const expiryResolution = int64(time.Second)
+
+// expiryIndex 按过期时间分桶的索引，清理时只处理已到期的桶，开销与到期的 key 数量成正比
+// 每个 key 只在一个桶中，重新写入时移动到新桶，删除时移出，到期时再检查 Store 中的实际过期时间
+type expiryIndex struct {
+	mutex   sync.Mutex
+	buckets map[int64]map[string]struct{}
+	// key 所在的桶
+	keys map[string]int64
+	// 有 key 的桶，最小堆
+	ticks tickHeap
+}
+
+func newExpiryIndex() *expiryIndex {
+	idx := expiryIndex{
+		buckets: make(map[int64]map[string]struct{}),
+		keys:    make(map[string]int64),
+	}
+	return &idx
+}
+
+// add 桶 t 存放过期时间在 ((t-1)*resolution, t*resolution] 的 key，到期时桶内 key 全部已过期
+func (idx *expiryIndex) add(key string, expire int64) {
+	tick := (expire + expiryResolution - 1) / expiryResolution
+
+	idx.mutex.Lock()
+	if old, ok := idx.keys[key]; ok {
+		if old == tick {
+			idx.mutex.Unlock()
+			return
+		}
+		// 旧桶为空时保留，到期时和堆中的 tick 一起删除
+		delete(idx.buckets[old], key)
+	}
+	idx.keys[key] = tick
+	bucket, ok := idx.buckets[tick]
+	if !ok {
+		bucket = make(map[string]struct{})
+		idx.buckets[tick] = bucket
+		heap.Push(&idx.ticks, tick)
+	}
+	bucket[key] = struct{}{}
+	idx.mutex.Unlock()
+}
+
+// expired 弹出所有到期桶中的 key
+func (idx *expiryIndex) expired(now int64) []string {
+	keys := make([]string, 0)
+
+	idx.mutex.Lock()
+	for len(idx.ticks) > 0 && idx.ticks[0]*expiryResolution <= now {
+		tick := heap.Pop(&idx.ticks).(int64)
+		for key := range idx.buckets[tick] {
+			keys = append(keys, key)
+			delete(idx.keys, key)
+		}
+		delete(idx.buckets, tick)
+	}
+	idx.mutex.Unlock()
+
+	return keys
+}
+
+// remove 删除或不再过期的 key 移出索引
+func (idx *expiryIndex) remove(key string) {
+	idx.mutex.Lock()
+	if tick, ok := idx.keys[key]; ok {
+		delete(idx.buckets[tick], key)
+		delete(idx.keys, key)
+	}
+	idx.mutex.Unlock()
+}
+
+func (idx *expiryIndex) flush() {
+	idx.mutex.Lock()
+	idx.buckets = make(map[int64]map[string]struct{})
+	idx.keys = make(map[string]int64)
+	idx.ticks = nil
+	idx.mutex.Unlock()
+}
+
+type tickHeap []int64
+
+func (h tickHeap) Len() int            { return len(h) }
+func (h tickHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h tickHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *tickHeap) Push(x interface{}) { *h = append(*h, x.(int64)) }
+
+func (h *tickHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,104 @@
+package gocache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryIndex(t *testing.T) {
+	idx := newExpiryIndex()
+	base := 100 * expiryResolution
+	idx.add("a", base+1)
+	idx.add("b", base+expiryResolution)
+	idx.add("c", base+2*expiryResolution)
+
+	if keys := idx.expired(base + 1); len(keys) != 0 {
+		t.Fatal("bucket should not expired before all keys expired ", keys)
+		return
+	}
+	keys := idx.expired(base + expiryResolution)
+	if len(keys) != 2 {
+		t.Fatal("expired keys error ", keys)
+		return
+	}
+	keys = idx.expired(base + 10*expiryResolution)
+	if len(keys) != 1 || keys[0] != "c" {
+		t.Fatal("expired keys error ", keys)
+		return
+	}
+	if len(idx.buckets) != 0 || len(idx.ticks) != 0 {
+		t.Fatal("buckets should empty")
+		return
+	}
+}
+
+func TestExpiryIndex_Move(t *testing.T) {
+	idx := newExpiryIndex()
+	base := 100 * expiryResolution
+	for i := int64(1); i <= 10; i++ {
+		idx.add("a", base+i*expiryResolution)
+	}
+	idx.add("b", base+expiryResolution)
+	if len(idx.keys) != 2 {
+		t.Fatal("index keys error ", len(idx.keys))
+		return
+	}
+	count := 0
+	for _, bucket := range idx.buckets {
+		count += len(bucket)
+	}
+	if count != 2 {
+		t.Fatal("key should only in one bucket ", count)
+		return
+	}
+
+	idx.remove("b")
+	if keys := idx.expired(base + 9*expiryResolution); len(keys) != 0 {
+		t.Fatal("moved or removed key should not expired ", keys)
+		return
+	}
+	if keys := idx.expired(base + 10*expiryResolution); len(keys) != 1 || keys[0] != "a" {
+		t.Fatal("expired keys error ", keys)
+		return
+	}
+
+	idx.add("c", base+20*expiryResolution)
+	idx.flush()
+	if len(idx.keys) != 0 || len(idx.buckets) != 0 {
+		t.Fatal("flush error")
+		return
+	}
+}
+
+func TestMemCacheImpl_ExpireIndex(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		ExpireIndex: true,
+		Clock:       clock,
+	})
+	cache.AutoCleanExpireKey(time.Second)
+	defer cache.Close()
+
+	_ = cache.Set("set", "1")
+	_ = cache.SetWithTTL("expire", "2", 50*time.Millisecond)
+	_ = cache.SetWithTTL("renew", "3", 50*time.Millisecond)
+	// 覆盖写入延长过期时间，索引到期后重新加入
+	_ = cache.SetWithTTL("renew", "3", time.Hour)
+
+	clock.Advance(2 * time.Second)
+	waitFor(func() bool { return cache.Size() == 2 })
+
+	if cache.Size() != 2 {
+		t.Fatal("ExpireIndex clean error ", cache.Size())
+		return
+	}
+	if _, ok := cache.Get("renew"); !ok {
+		t.Fatal("renew should exists")
+		return
+	}
+	if cache.Stats().Expired != 1 {
+		t.Fatal("expired stats error ", cache.Stats())
+		return
+	}
+}
@@ -0,0 +1,58 @@
+package gocache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ttlJitter 随机分散过期时间，避免同时写入的 key 在同一时刻过期
+type ttlJitter struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func newTTLJitter(source rand.Source) *ttlJitter {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &ttlJitter{rand: rand.New(source)}
+}
+
+// spread 在 [ttl*(1-fraction), ttl*(1+fraction)) 内随机，fraction 超过 1 按 1 处理
+// ttl <= 0 或 fraction <= 0 时不变，结果至少为 1ns，不会变成永久有效或不写入
+func (j *ttlJitter) spread(ttl time.Duration, fraction float64) time.Duration {
+	if ttl <= 0 || fraction <= 0 {
+		return ttl
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	j.mutex.Lock()
+	r := j.rand.Float64()
+	j.mutex.Unlock()
+
+	d := ttl + time.Duration(float64(ttl)*fraction*(2*r-1))
+	if d <= 0 {
+		d = 1
+	}
+	return d
+}
+
+// float64 (0, 1] 内随机
+func (j *ttlJitter) float64() float64 {
+	j.mutex.Lock()
+	r := j.rand.Float64()
+	j.mutex.Unlock()
+	return 1 - r
+}
+
+// SetWithTTLJitter 写入时按 ±fraction 随机分散 ttl，例如 0.1 为 ±10%，忽略 Config.TTLJitter
+func (mem *MemCache) SetWithTTLJitter(key string, value interface{}, ttl time.Duration, fraction float64) error {
+	if ttl == 0 {
+		return nil
+	}
+	ttl = mem.jitter.spread(ttl, fraction)
+	return mem.write(key, newExpireValue(value, ttl, mem.sizeof(key, value), mem.now()))
+}
@@ -0,0 +1,176 @@
+package gocache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTTLJitter_Spread(t *testing.T) {
+	j := newTTLJitter(rand.NewSource(1))
+	ttl := 100 * time.Second
+	distinct := map[time.Duration]struct{}{}
+	for i := 0; i < 1000; i++ {
+		d := j.spread(ttl, 0.1)
+		if d < 90*time.Second || d >= 110*time.Second {
+			t.Fatal("spread out of range ", d)
+			return
+		}
+		distinct[d] = struct{}{}
+	}
+	if len(distinct) < 900 {
+		t.Fatal("spread not random ", len(distinct))
+		return
+	}
+
+	if j.spread(NoExpiration, 0.1) != NoExpiration || j.spread(ttl, 0) != ttl {
+		t.Fatal("spread should not change")
+		return
+	}
+	for i := 0; i < 100; i++ {
+		if j.spread(time.Nanosecond, 5) <= 0 {
+			t.Fatal("spread should > 0")
+			return
+		}
+	}
+
+	// 相同种子结果相同
+	a, b := newTTLJitter(rand.NewSource(7)), newTTLJitter(rand.NewSource(7))
+	for i := 0; i < 10; i++ {
+		if a.spread(ttl, 0.5) != b.spread(ttl, 0.5) {
+			t.Fatal("same seed should same result")
+			return
+		}
+	}
+}
+
+func TestMemCacheImpl_TTLJitter(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		TTLJitter:    0.2,
+		JitterSource: rand.NewSource(1),
+	})
+	for i := 0; i < 100; i++ {
+		_ = cache.SetWithExpire(fmt.Sprintf("%d", i), i, 100)
+	}
+	_ = cache.Set("forever", 1)
+
+	distinct := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		ttl, _ := cache.TTL(fmt.Sprintf("%d", i))
+		if ttl < 79*time.Second || ttl >= 120*time.Second {
+			t.Fatal("jitter out of range ", ttl)
+			return
+		}
+		distinct[ttl/time.Second] = struct{}{}
+	}
+	if len(distinct) < 10 {
+		t.Fatal("jitter not spread ", len(distinct))
+		return
+	}
+	if ttl, _ := cache.TTL("forever"); ttl != NoExpiration {
+		t.Fatal("forever key should not jitter")
+		return
+	}
+
+	// 单次调用指定比例
+	_ = cache.SetWithTTLJitter("call", 1, time.Hour, 0.5)
+	if ttl, _ := cache.TTL("call"); ttl < 30*time.Minute-time.Second || ttl >= 90*time.Minute {
+		t.Fatal("call jitter out of range ", ttl)
+		return
+	}
+}
+
+func TestMemCacheImpl_TTLJitterLoadFromDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cache.gob")
+
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Filename: filename})
+	for i := 0; i < 100; i++ {
+		_ = cache.SetWithExpire(fmt.Sprintf("%d", i), i, 100)
+	}
+	if err := cache.WriteToDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	cache = NewSyncMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		Filename:     filename,
+		TTLJitter:    0.5,
+		JitterSource: rand.NewSource(1),
+	})
+	if err := cache.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	distinct := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		ttl, ok := cache.TTL(fmt.Sprintf("%d", i))
+		if !ok || ttl < 49*time.Second || ttl >= 150*time.Second {
+			t.Fatal("load jitter out of range ", ttl)
+			return
+		}
+		distinct[ttl/time.Second] = struct{}{}
+	}
+	if len(distinct) < 20 {
+		t.Fatal("load jitter not spread ", len(distinct))
+		return
+	}
+}
+
+func TestMemCacheImpl_PinnedLoadFromDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	clock := NewFakeClock(time.Now())
+	config := Config{
+		LimitSize: -1,
+		Filename:  filepath.Join(dir, "cache.gob"),
+		TTLJitter: 0.5,
+		Clock:     clock,
+	}
+	cache := NewSyncMapCacheWithConfig(config)
+	_ = cache.SetWithTTL("pin", 1, time.Second)
+	if !cache.Pin("pin") {
+		t.Fatal("pin error")
+		return
+	}
+	// 固定的 key 超过原过期时间后落盘
+	clock.Advance(2 * time.Second)
+	if err := cache.WriteToDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	loaded := NewSyncMapCacheWithConfig(config)
+	if err := loaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if _, ok := loaded.Get("pin"); !ok {
+		t.Fatal("pinned key should loaded")
+		return
+	}
+	if !loaded.Unpin("pin") {
+		t.Fatal("unpin error")
+		return
+	}
+	if _, ok := loaded.Get("pin"); ok {
+		t.Fatal("unpinned key should expired")
+		return
+	}
+}
@@ -0,0 +1,144 @@
+package gocache
+
+import (
+	"container/heap"
+	"sync"
+)
+
+const (
+	// 访问次数达到 key 数量的倍数后，所有计数减半，让过去的热点 key 逐渐冷却
+	lfuAgingFactor = 10
+	lfuAgingMin    = 1024
+)
+
+// LFUPolicy 最不经常使用淘汰，带老化
+// 计数相同时淘汰最久未访问的 key
+type LFUPolicy struct {
+	mutex sync.Mutex
+	heap  lfuHeap
+	items map[string]*lfuItem
+	// 逻辑时钟，区分计数相同的 key
+	tick uint64
+	// 上次老化后的访问次数
+	accesses int
+}
+
+type lfuItem struct {
+	key   string
+	freq  uint32
+	tick  uint64
+	index int
+}
+
+func NewLFUPolicy() *LFUPolicy {
+	l := LFUPolicy{
+		items: make(map[string]*lfuItem),
+	}
+	return &l
+}
+
+func (l *LFUPolicy) Access(key string) {
+	l.mutex.Lock()
+	if item, ok := l.items[key]; ok {
+		l.tick++
+		item.tick = l.tick
+		if item.freq < ^uint32(0) {
+			item.freq++
+		}
+		heap.Fix(&l.heap, item.index)
+		l.aging()
+	}
+	l.mutex.Unlock()
+}
+
+func (l *LFUPolicy) Insert(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.items[key]; ok {
+		return
+	}
+	l.tick++
+	item := &lfuItem{key: key, freq: 1, tick: l.tick}
+	heap.Push(&l.heap, item)
+	l.items[key] = item
+}
+
+func (l *LFUPolicy) Delete(key string) {
+	l.mutex.Lock()
+	if item, ok := l.items[key]; ok {
+		heap.Remove(&l.heap, item.index)
+		delete(l.items, key)
+	}
+	l.mutex.Unlock()
+}
+
+// Evict 弹出访问计数最小的 key
+func (l *LFUPolicy) Evict() (key string, ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.heap.Len() == 0 {
+		return "", false
+	}
+	item := heap.Pop(&l.heap).(*lfuItem)
+	delete(l.items, item.key)
+	return item.key, true
+}
+
+func (l *LFUPolicy) Flush() {
+	l.mutex.Lock()
+	l.heap = nil
+	l.items = make(map[string]*lfuItem)
+	l.accesses = 0
+	l.mutex.Unlock()
+}
+
+// aging 计数减半，减半后不同的计数可能相同，按访问时间重新排序，需要重建堆
+func (l *LFUPolicy) aging() {
+	l.accesses++
+	limit := len(l.items) * lfuAgingFactor
+	if limit < lfuAgingMin {
+		limit = lfuAgingMin
+	}
+	if l.accesses < limit {
+		return
+	}
+	l.accesses = 0
+	for _, item := range l.heap {
+		item.freq = item.freq/2 + 1
+	}
+	heap.Init(&l.heap)
+}
+
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq == h[j].freq {
+		return h[i].tick < h[j].tick
+	}
+	return h[i].freq < h[j].freq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
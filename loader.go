@@ -0,0 +1,185 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound loader 返回 ErrNotFound 表示数据不存在，开启 Config.NegativeTTL 时缓存不存在的结果
+	ErrNotFound = errors.New("not found")
+
+	errLoaderPanic = errors.New("loader panic")
+)
+
+// Loader 缓存未命中时加载数据，返回值和过期时间，ttl < 0 (NoExpiration) 永久有效，ttl == 0 不写入缓存
+type Loader func(ctx context.Context) (value interface{}, ttl time.Duration, err error)
+
+// loadCall 正在执行的加载，done 关闭后 value、err 可读
+type loadCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// loadGroup 同一个 key 同时只执行一次加载，其它调用等待并共享结果
+type loadGroup struct {
+	mutex sync.Mutex
+	calls map[string]*loadCall
+}
+
+// do 等待中的调用可以通过 ctx 放弃等待，不影响正在执行的加载
+func (g *loadGroup) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		select {
+		case <-c.done:
+			return c.value, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &loadCall{done: make(chan struct{}), err: errLoaderPanic}
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	// fn panic 时等待的调用返回 errLoaderPanic，panic 继续向上传递
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err
+}
+
+// GetOrLoad 读取 key，不存在时调用 loader 加载并写入缓存
+// 开启 Config.RefreshAfter 时，写入超过 RefreshAfter 后读取，返回当前值并在后台使用 loader 重新加载
+// 同一个 key 并发调用只执行一次 loader，其它调用等待并共享结果，loader 使用第一个调用的 ctx
+// loader 的错误返回给所有等待的调用，不会缓存；写入缓存失败时返回加载的值和写入错误
+// 开启 Config.StaleGrace 时，loader 失败会返回宽限期内的过期值，需要区分时使用 GetOrLoadStale
+// 开启 Config.NegativeTTL 时，loader 返回 ErrNotFound 会缓存不存在的结果，期间直接返回 ErrNotFound
+func (mem *MemCache) GetOrLoad(ctx context.Context, key string, loader Loader) (interface{}, error) {
+	value, _, err := mem.GetOrLoadStale(ctx, key, loader)
+	return value, err
+}
+
+// loadResult 加载结果，stale 为 true 时 value 是宽限期内的过期值
+type loadResult struct {
+	value interface{}
+	stale bool
+}
+
+// GetOrLoadStale 与 GetOrLoad 相同，loader 失败且 key 过期不超过 Config.StaleGrace 时
+// 返回过期的旧值，stale 为 true，err 为 nil，旧值不会延长过期时间
+// 开启 Config.XFetchBeta 时，未过期的 key 也可能提前重新加载，loader 失败时返回当前值
+func (mem *MemCache) GetOrLoadStale(ctx context.Context, key string, loader Loader) (value interface{}, stale bool, err error) {
+	// 未命中时由 loader 加载，不经过 Backend 读穿透
+	ev, ok := mem.getCached(key)
+	if ok && !mem.xfetch(ev, mem.now()) {
+		return ev.Value, false, nil
+	}
+	early := ok
+
+	v, err := mem.loads.do(ctx, key, func() (interface{}, error) {
+		// 等待期间其它加载可能已经写入，提前重新加载时不检查
+		if ev, ok := mem.peek(key); ok && !early {
+			if ev.Tombstone {
+				return nil, ErrNotFound
+			}
+			return loadResult{value: ev.Value}, nil
+		}
+		start := mem.now()
+		value, ttl, err := loader(ctx)
+		if err == ErrNotFound {
+			return nil, mem.setNotFound(key)
+		}
+		if err != nil {
+			if ev, ok := mem.peekStale(key); ok && !ev.Tombstone {
+				return loadResult{value: ev.Value, stale: ev.isExpire(mem.now())}, nil
+			}
+			return nil, err
+		}
+		return loadResult{value: value}, mem.setLoaded(key, value, ttl, mem.now()-start, loader)
+	})
+	if r, ok := v.(loadResult); ok {
+		return r.value, r.stale, err
+	}
+	return nil, false, err
+}
+
+// setLoaded 写入 loader 加载的值和加载耗时 delta，开启 RefreshAfter 时记录刷新时间和 loader
+func (mem *MemCache) setLoaded(key string, value interface{}, ttl time.Duration, delta int64, loader Loader) error {
+	if ttl == 0 {
+		return nil
+	}
+	now := mem.now()
+	ev := mem.ttlValue(key, value, ttl)
+	ev.Delta = delta
+	if mem.refresher != nil {
+		refreshAt := now + int64(mem.refreshAfter)
+		// 刷新时间晚于过期时间时不刷新
+		if ev.Expire == -1 || refreshAt < ev.Expire {
+			ev.RefreshAt = refreshAt
+			mem.refresher.loaders.Store(key, loader)
+		}
+	}
+	return mem.set(key, ev)
+}
+
+// setNotFound 开启 NegativeTTL 时写入不存在的标记，返回 ErrNotFound
+// 墓碑成本为 0，不计入 Size、LimitSize，不参与淘汰，到期后清理
+func (mem *MemCache) setNotFound(key string) error {
+	if mem.negativeTTL <= 0 {
+		return ErrNotFound
+	}
+	ev := newExpireValue(nil, mem.negativeTTL, 0, mem.now())
+	ev.Tombstone = true
+	// 墓碑只是优化，写入失败(如超过容量)时同样返回 ErrNotFound
+	_ = mem.set(key, ev)
+	return ErrNotFound
+}
+
+// xfetch 概率提前过期(XFetch)，now - delta * beta * ln(rand) >= expire 时重新加载
+// 加载耗时越长、越接近过期，概率越高，避免大量请求在过期时同时加载
+func (mem *MemCache) xfetch(ev expireValue, now int64) bool {
+	if mem.xfetchBeta <= 0 || ev.Delta <= 0 || ev.Expire == -1 || ev.Pinned {
+		return false
+	}
+	gap := -float64(ev.Delta) * mem.xfetchBeta * math.Log(mem.jitter.float64())
+	return float64(now)+gap >= float64(ev.Expire)
+}
+
+// peek 读取未过期的值，不计入统计，不算作访问
+func (mem *MemCache) peek(key string) (expireValue, bool) {
+	v, ok := mem.store.Load(key)
+	if !ok {
+		return expireValue{}, false
+	}
+	ev := v.(expireValue)
+	if ev.isExpire(mem.now()) {
+		return expireValue{}, false
+	}
+	return ev, true
+}
+
+// peekStale 读取未过期或过期不超过 StaleGrace 的值
+func (mem *MemCache) peekStale(key string) (expireValue, bool) {
+	v, ok := mem.store.Load(key)
+	if !ok {
+		return expireValue{}, false
+	}
+	ev := v.(expireValue)
+	if ev.isExpire(mem.now() - int64(mem.staleGrace)) {
+		return expireValue{}, false
+	}
+	return ev, true
+}
@@ -0,0 +1,135 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_GetOrLoad(t *testing.T) {
+	cache := NewRWMapCache()
+
+	var calls int64
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return "db", time.Minute, nil
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.GetOrLoad(context.Background(), "key", loader)
+			if err != nil || v.(string) != "db" {
+				errs <- errors.New("load result error")
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+		return
+	}
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatal("loader should call once ", calls)
+		return
+	}
+	if ttl, ok := cache.TTL("key"); !ok || ttl <= 59*time.Second {
+		t.Fatal("loaded value should cached ", ttl)
+		return
+	}
+
+	// 命中缓存不再加载
+	_, _ = cache.GetOrLoad(context.Background(), "key", loader)
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatal("cached key should not load ", calls)
+		return
+	}
+}
+
+func TestMemCacheImpl_GetOrLoadError(t *testing.T) {
+	cache := NewSyncMapCache()
+
+	errDB := errors.New("db error")
+	var calls int64
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return nil, 0, errDB
+	}
+
+	wg := sync.WaitGroup{}
+	var failed int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetOrLoad(context.Background(), "key", loader); err == errDB {
+				atomic.AddInt64(&failed, 1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if failed != 10 || calls != 1 {
+		t.Fatal("error should return to all waiters ", failed, calls)
+		return
+	}
+	if _, ok := cache.Get("key"); ok {
+		t.Fatal("error should not cached")
+		return
+	}
+	// 错误不缓存，再次调用重新加载
+	_, _ = cache.GetOrLoad(context.Background(), "key", loader)
+	if calls != 2 {
+		t.Fatal("should load again ", calls)
+		return
+	}
+}
+
+func TestMemCacheImpl_GetOrLoadContext(t *testing.T) {
+	cache := NewSyncMapCache()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _ = cache.GetOrLoad(context.Background(), "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+			close(started)
+			<-release
+			return "1", NoExpiration, nil
+		})
+	}()
+	<-started
+
+	// 等待的调用取消，不影响正在执行的加载
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetOrLoad(ctx, "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+		t.Fatal("waiter should not load")
+		return nil, 0, nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatal("waiter should canceled ", err)
+		return
+	}
+
+	close(release)
+	if !waitFor(func() bool { _, ok := cache.Get("key"); return ok }) {
+		t.Fatal("loaded value should cached")
+		return
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/gob"
 	"errors"
 	"log"
+	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,18 +19,97 @@ Notice:
 	1. 使用 sync.Map 实现，最大限度优化读的性能，特别是在 cpu > 4核的情况下，因为没有锁的竞争，读取优势很明显
 	但是内存消耗严重(约等于2倍)，空间换时间。而且写入性能没有 rwMutex map 性能好
 	2. 当存在大量的 TTLKey 时，不建议总缓存Key超过百万，在清理TTLKey时会占用锁，会有一定的写入延迟
+	开启 Config.ExpireIndex 后，清理只处理到期的 key，不再遍历全部数据
 	3. 不建议缓存内容过大时，执行写入磁盘操作，写入时会进行编码，会申请内容的一倍内存，如果内存不够，会导致OOM
 */
 
+// NoExpiration SetWithTTL 永久有效
+const NoExpiration time.Duration = -1
+
+// 写入 key 的分段锁数量
+const keyLockStripes = 256
+
 var (
 	ErrKeysOverLimitSize = errors.New("keys over limit size")
+	ErrOverLimitBytes    = errors.New("over limit bytes")
+	// 未开启 Config.ActiveExpire 时调用 AutoActiveExpireKey
+	ErrActiveExpireDisabled = errors.New("active expire disabled")
+)
+
+// EvictMode 超过容量时的处理方式
+type EvictMode int
+
+const (
+	// EvictNone 不淘汰，超过容量写入报错 ErrKeysOverLimitSize
+	EvictNone EvictMode = iota
+	// EvictLRU 淘汰最近最少使用的 key，写入总是成功
+	EvictLRU
+	// EvictLFU 淘汰访问次数最少的 key，计数会老化
+	EvictLFU
+	// EvictFIFO 淘汰最早写入的 key
+	EvictFIFO
+	// EvictRandom 随机采样，淘汰样本中最久未访问的 key
+	EvictRandom
+	// EvictTinyLFU W-TinyLFU，新 key 需要比淘汰候选访问频率高才会被保留，适合访问倾斜、有批量扫描的场景
+	// 注意写入成功的新 key 可能立即被淘汰
+	EvictTinyLFU
 )
 
 type Config struct {
 	// 缓存容量， -1 - 不限制
 	LimitSize int64
+	// 超过容量时的处理方式，默认 EvictNone
+	Evict EvictMode
+	// 自定义淘汰策略，设置后忽略 Evict
+	EvictionPolicy EvictionPolicy
+	// 内存预算(字节)，超过后按淘汰策略淘汰，未设置淘汰策略时写入报错 ErrOverLimitBytes， <= 0 - 不限制
+	// SetWithCost 指定的成本同样计入预算
+	LimitBytes int64
+	// 估算 value 占用的字节数，默认 DefaultSizer，key 的长度会计算在内
+	Sizer Sizer
 	// 保存文件位置, 不设置，默认当前执行路径
 	Filename string
+	// 按过期时间分桶索引 TTL key，AutoCleanExpireKey 只处理到期的 key，可以每秒执行
+	// 每次写入 TTL key 会额外占用索引锁和内存
+	ExpireIndex bool
+	// 记录所有 TTL key，用于 AutoActiveExpireKey 随机采样主动过期
+	ActiveExpire bool
+	// 主动过期每轮采样数量，默认 20
+	ActiveExpireSamples int
+	// 主动过期每次执行的时间预算，默认 25ms
+	ActiveExpireBudget time.Duration
+	// 过期时间随机分散比例，例如 0.1 - 实际过期时间在 ttl 的 ±10% 内随机，<= 0 不分散
+	// 作用于 SetWithExpire、SetWithTTL、SetWithCost 和 LoadFromDisk，避免同时写入的 key 同时过期
+	TTLJitter float64
+	// 分散过期时间和 XFetch 使用的随机数源，测试时可以固定种子，默认按当前时间
+	JitterSource rand.Source
+	// 时间来源，默认系统时间，测试时可以使用 FakeClock
+	Clock Clock
+	// GetOrLoad 加载的 key 写入超过 RefreshAfter 后读取，返回当前值并在后台重新加载，<= 0 不刷新
+	// 刷新时间晚于过期时间时不刷新
+	RefreshAfter time.Duration
+	// 后台刷新的并发数，默认 4，Close 时停止
+	RefreshWorkers int
+	// 过期的 key 保留的宽限期，GetOrLoad 的 loader 失败时返回宽限期内的过期值，<= 0 不保留
+	// 宽限期内的 key 读取时不存在，但仍计入 Size 和容量，宽限期结束后才会被清理
+	StaleGrace time.Duration
+	// GetOrLoad 概率提前过期(XFetch)的 beta，> 1 更倾向提前加载，< 1 更倾向接近过期时加载，<= 0 不开启，建议 1
+	// 根据 loader 的加载耗时，越接近过期时越可能由某一次读取提前重新加载
+	XFetchBeta float64
+	// GetOrLoad 的 loader 返回 ErrNotFound 时，缓存不存在的结果的时间，<= 0 不缓存
+	// 期间 Get 返回不存在，Keys 不包含，GetOrLoad 直接返回 ErrNotFound 不再调用 loader
+	NegativeTTL time.Duration
+	// 缓存后面的持久化存储，按 BackendMode 读写，nil 不使用
+	// 淘汰、过期和 FlushAll 只作用于缓存，不会删除 Backend 中的数据
+	Backend Backend
+	// 与 Backend 同步的方式
+	BackendMode BackendMode
+	// read-through 加载的 key 的过期时间，<= 0 永久有效
+	BackendTTL time.Duration
+	// write-behind 批量写入间隔，默认 1s
+	WriteBehindInterval time.Duration
+	// write-behind 写入失败的重试次数，默认 3
+	WriteBehindRetries int
 }
 
 func NewRWMapCache() *MemCache {
@@ -47,6 +128,23 @@ func NewSyncMapCacheWithConfig(config Config) *MemCache {
 	return NewMemCacheWithConfig(NewSyncMap(), config)
 }
 
+func NewShardedMapCache() *MemCache {
+	return NewMemCache(NewShardedMap(defaultShards))
+}
+
+func NewShardedMapCacheWithConfig(config Config) *MemCache {
+	return NewMemCacheWithConfig(NewShardedMap(defaultShards), config)
+}
+
+// NewByteStoreCache capacity ByteStore 缓冲区总字节数，值使用 GobCodec 编码
+func NewByteStoreCache(capacity int64) *MemCache {
+	return NewMemCache(NewByteStore(capacity, nil))
+}
+
+func NewByteStoreCacheWithConfig(capacity int64, config Config) *MemCache {
+	return NewMemCacheWithConfig(NewByteStore(capacity, nil), config)
+}
+
 func NewMemCache(store Store) *MemCache {
 	return NewMemCacheWithConfig(store, Config{
 		LimitSize: -1,
@@ -55,13 +153,70 @@ func NewMemCache(store Store) *MemCache {
 
 func NewMemCacheWithConfig(store Store, config Config) *MemCache {
 	mem := MemCache{
-		limitSize: config.LimitSize,
+		limitSize:  config.LimitSize,
+		limitBytes: config.LimitBytes,
+		sizer:      config.Sizer,
 
 		store: store,
 
 		disk: NewDisk(config.Filename),
 
-		exit: make(chan int, 1),
+		policy: newEvictionPolicy(config),
+
+		ttlJitter: config.TTLJitter,
+		jitter:    newTTLJitter(config.JitterSource),
+
+		clock: config.Clock,
+
+		staleGrace: config.StaleGrace,
+		xfetchBeta: config.XFetchBeta,
+
+		negativeTTL: config.NegativeTTL,
+
+		backend:    config.Backend,
+		backendTTL: config.BackendTTL,
+
+		exit: make(chan int),
+	}
+
+	if mem.clock == nil {
+		mem.clock = realClock{}
+	}
+	if mem.sizer == nil && mem.limitBytes > 0 {
+		mem.sizer = DefaultSizer
+	}
+	if bs, ok := store.(*ByteStore); ok {
+		bs.setOnEvict(mem.storeEvicted)
+	}
+	if config.ExpireIndex {
+		mem.expiry = newExpiryIndex()
+	}
+	if config.ActiveExpire {
+		mem.ttlKeys = newTTLKeySet()
+		mem.activeExpireSamples = config.ActiveExpireSamples
+		if mem.activeExpireSamples <= 0 {
+			mem.activeExpireSamples = defaultActiveExpireSamples
+		}
+		mem.activeExpireBudget = config.ActiveExpireBudget
+		if mem.activeExpireBudget <= 0 {
+			mem.activeExpireBudget = defaultActiveExpireBudget
+		}
+	}
+
+	if mem.backend != nil {
+		mem.backendMode = config.BackendMode
+		if mem.backendTTL <= 0 {
+			mem.backendTTL = NoExpiration
+		}
+		if mem.backendMode&BackendWriteThrough == 0 && mem.backendMode&BackendWriteBehind != 0 {
+			mem.behind = newWriteBehind(mem.backend, config.WriteBehindRetries)
+			mem.startWriteBehind(config.WriteBehindInterval)
+		}
+	}
+	if config.RefreshAfter > 0 {
+		mem.refreshAfter = config.RefreshAfter
+		mem.refresher = newRefresher()
+		mem.startRefreshWorkers(config.RefreshWorkers)
 	}
 
 	return &mem
@@ -71,53 +226,157 @@ func NewMemCacheWithConfig(store Store, config Config) *MemCache {
 	MemCache
 */
 type MemCache struct {
+	// 当前存储内容的总成本，atomic 操作，放在首位保证 32 位系统下 64 位对齐
+	cost int64
+	// 统计计数，atomic 操作
+	pinned    int64
+	hits      int64
+	misses    int64
+	evictions int64
+	expired   int64
+	// Store 中的墓碑(Tombstone)数量，不计入 Size
+	tombstones int64
+
 	// Key  limit cap, default -1 not limit
 	limitSize int64
+	// 内存预算， <= 0 不限制
+	limitBytes int64
+	// 估算 value 大小，nil 时每个 key 成本为 1
+	sizer Sizer
 
 	// 存储所有数据 key , value - expireValue
 	store Store
+	// 按 key 分段的写锁，保证 Store 中同一个 key 的 读取-修改-写回 不会覆盖并发写入，读取不加锁
+	locks [keyLockStripes]sync.Mutex
+
+	// 淘汰策略，nil 不淘汰
+	policy EvictionPolicy
+
+	// 过期索引，nil 时清理过期 key 需要遍历全部数据
+	expiry *expiryIndex
+
+	// 主动过期采样的 TTL key 集合，nil 不开启
+	ttlKeys             *ttlKeySet
+	activeExpireSamples int
+	activeExpireBudget  time.Duration
+
+	// 过期时间随机分散比例
+	ttlJitter float64
+	jitter    *ttlJitter
+
+	// 时间来源
+	clock Clock
+
+	// GetOrLoad 正在执行的加载
+	loads loadGroup
+	// 后台刷新，nil 不刷新
+	refreshAfter time.Duration
+	refresher    *refresher
+	// 过期 key 保留的宽限期
+	staleGrace time.Duration
+	// XFetch beta，<= 0 不开启
+	xfetchBeta float64
+	// 不存在的结果缓存时间，<= 0 不缓存
+	negativeTTL time.Duration
+
+	// 持久化存储，nil 时 backendMode 为 0
+	backend     Backend
+	backendMode BackendMode
+	backendTTL  time.Duration
+	// write-behind 等待写入的 key，nil 不开启
+	behind *writeBehind
+
+	// name - *Namespace
+	namespaces sync.Map
+
+	// 移除 key 时的回调
+	callbackMutex sync.RWMutex
+	callbacks     []RemovalFunc
 
 	// 写入磁盘
 	disk *Disk
 
-	once sync.Once
+	once       sync.Once
+	activeOnce sync.Once
+	closeOnce  sync.Once
 
 	exit chan int
 }
 
 type expireValue struct {
-	Value  interface{}
-	Expire int64 // expire time /sec  -1 never expire
+	Value     interface{}
+	Expire    int64 // expire time /nanosecond  -1 never expire
+	Cost      int64 // 成本，默认为占用字节数
+	Pinned    bool  // 固定，不会被淘汰和过期
+	Idle      int64 // 滑动过期的空闲时间 /nanosecond，读取时延长 Expire，0 不滑动
+	MaxExpire int64 // 滑动过期的最长有效期限 /nanosecond，0 不限制
+	RefreshAt int64 // 后台刷新时间 /nanosecond，0 不刷新
+	Delta     int64 // loader 加载耗时 /nanosecond，用于 XFetch
+	Tombstone bool  // loader 返回 ErrNotFound 的标记，读取时不存在
 }
 
-func (ev *expireValue) ttl(ttl int64) {
-	if ttl <= -1 {
+func newExpireValue(value interface{}, ttl time.Duration, cost int64, now int64) expireValue {
+	ev := expireValue{
+		Value: value,
+		Cost:  cost,
+	}
+	ev.ttl(ttl, now)
+	return ev
+}
+
+func (ev *expireValue) ttl(ttl time.Duration, now int64) {
+	if ttl < 0 {
 		ev.Expire = -1
 		return
 	}
-	ev.Expire = time.Now().Unix() + ttl
+	ev.Expire = now + int64(ttl)
 }
 
-func (ev expireValue) surplusSec(nowSec int64) int64 {
+// surplus 剩余有效时间，-1 永久有效
+func (ev expireValue) surplus(now int64) time.Duration {
 	if ev.Expire == -1 {
-		return ev.Expire
+		return NoExpiration
 	}
-	expire := ev.Expire - nowSec
+	expire := ev.Expire - now
 	// 存在这种可能
 	if expire < 0 {
 		expire = 0
 	}
 
-	return expire
+	return time.Duration(expire)
+}
+
+// slide 滑动过期，从 now 起延长 Idle，不超过 MaxExpire，过期时间有变化返回 true
+func (ev *expireValue) slide(now int64) bool {
+	if ev.Idle <= 0 {
+		return false
+	}
+	expire := now + ev.Idle
+	if ev.MaxExpire > 0 && expire > ev.MaxExpire {
+		expire = ev.MaxExpire
+	}
+	if expire <= ev.Expire {
+		return false
+	}
+	ev.Expire = expire
+	return true
 }
 
 // true - expired
-// nowSec Avoid frequent timing
-func (ev expireValue) isExpire(nowSec int64) bool {
-	if ev.Expire == -1 {
+// now Avoid frequent timing
+func (ev expireValue) isExpire(now int64) bool {
+	if ev.Pinned || ev.Expire == -1 {
 		return false
 	}
-	return nowSec >= ev.Expire
+	return now >= ev.Expire
+}
+
+// secondsToTTL 秒级别 ttl 转换为 time.Duration，<= -1 永久有效
+func secondsToTTL(ttl int64) time.Duration {
+	if ttl <= -1 {
+		return NoExpiration
+	}
+	return time.Duration(ttl) * time.Second
 }
 
 func (mem *MemCache) Get(key string) (value interface{}, ok bool) {
@@ -138,7 +397,16 @@ func (mem *MemCache) GetWithExpire(key string) (value interface{}, ttl int64, ok
 		return v.Value, v.Expire, ok
 	}
 
-	return v.Value, v.surplusSec(time.Now().Unix()), ok
+	return v.Value, int64(v.surplus(mem.now()) / time.Second), ok
+}
+
+// GetWithTTL 返回值和剩余有效时间，永久有效时为 NoExpiration
+func (mem *MemCache) GetWithTTL(key string) (value interface{}, ttl time.Duration, ok bool) {
+	v, ok := mem.getValue(key)
+	if !ok {
+		return nil, 0, ok
+	}
+	return v.Value, v.surplus(mem.now()), ok
 }
 
 func (mem *MemCache) Set(key string, value interface{}) error {
@@ -147,20 +415,90 @@ func (mem *MemCache) Set(key string, value interface{}) error {
 
 // SetWithExpire  ttl - 过期时间秒级别， -1 永久有效
 func (mem *MemCache) SetWithExpire(key string, value interface{}, ttl int64) error {
+	return mem.SetWithTTL(key, value, secondsToTTL(ttl))
+}
+
+// SetWithTTL  ttl - 过期时间，纳秒精度， < 0 (NoExpiration) 永久有效， 0 不写入
+func (mem *MemCache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if ttl == 0 {
+		return nil
+	}
+	return mem.write(key, mem.ttlValue(key, value, ttl))
+}
+
+// ttlValue 按 Config.TTLJitter 分散 ttl
+func (mem *MemCache) ttlValue(key string, value interface{}, ttl time.Duration) expireValue {
+	ttl = mem.jitter.spread(ttl, mem.ttlJitter)
+	return newExpireValue(value, ttl, mem.sizeof(key, value), mem.now())
+}
+
+// SetWithIdleTimeout 滑动过期，最后一次读取后 idle 时间内有效，Get、GetWithExpire、GetWithTTL 会延长过期时间
+// maxLifetime > 0 时，从写入起最长有效 maxLifetime，不会再延长； idle <= 0 时不滑动，等同于 SetWithTTL(maxLifetime)
+func (mem *MemCache) SetWithIdleTimeout(key string, value interface{}, idle, maxLifetime time.Duration) error {
+	if maxLifetime <= 0 {
+		maxLifetime = NoExpiration
+	}
+	if idle <= 0 {
+		return mem.SetWithTTL(key, value, maxLifetime)
+	}
+
+	now := mem.now()
+	ev := newExpireValue(value, idle, mem.sizeof(key, value), now)
+	ev.Idle = int64(idle)
+	if maxLifetime > 0 {
+		ev.MaxExpire = now + int64(maxLifetime)
+		if ev.Expire > ev.MaxExpire {
+			ev.Expire = ev.MaxExpire
+		}
+	}
+	return mem.write(key, ev)
+}
+
+// SetWithCost 指定成本写入，例如计算代价高的内容，成本与 LimitBytes 同单位
+// 淘汰策略会持续淘汰直到总成本不超过 LimitBytes
+func (mem *MemCache) SetWithCost(key string, value interface{}, cost int64, ttl int64) error {
+	if ttl == 0 {
+		return nil
+	}
+	return mem.write(key, newExpireValue(value, mem.jitter.spread(secondsToTTL(ttl), mem.ttlJitter), cost, mem.now()))
+}
+
+// set 检查容量后写入，覆盖固定的 key 时保持固定
+func (mem *MemCache) set(key string, ev expireValue) error {
+	if !ev.Pinned && atomic.LoadInt64(&mem.pinned) > 0 {
+		if v, ok := mem.store.Load(key); ok {
+			ev.Pinned = v.(expireValue).Pinned
+		}
+	}
 
-	if mem.limitSize >= 0 {
+	// 墓碑不计入 LimitSize
+	if mem.policy == nil && mem.limitSize >= 0 && !ev.Tombstone {
 		if mem.Size() >= mem.limitSize {
 			return ErrKeysOverLimitSize
 		}
 	}
 
-	mem.setValue(key, value, ttl)
+	if mem.limitBytes > 0 {
+		if ev.Cost > mem.limitBytes {
+			return ErrOverLimitBytes
+		}
+		if mem.policy == nil {
+			cost := ev.Cost
+			if v, ok := mem.store.Load(key); ok {
+				cost -= v.(expireValue).Cost
+			}
+			if mem.Cost()+cost > mem.limitBytes {
+				return ErrOverLimitBytes
+			}
+		}
+	}
 
-	return nil
+	return mem.setValue(key, ev)
 }
 
 func (mem *MemCache) Delete(key string) {
-	mem.store.Delete(key)
+	mem.deleteBackend(key)
+	mem.removeValue(key, RemovalDeleted)
 }
 
 type iKeys struct {
@@ -180,9 +518,9 @@ func (mem *MemCache) Keys(prefix string) Keys {
 	keys := iKeys{
 		keys: make([]string, 0, mem.store.Size()),
 	}
-	nowSec := time.Now().Unix()
+	now := mem.now()
 	mem.store.Range(func(k string, v interface{}) bool {
-		if !v.(expireValue).isExpire(nowSec) {
+		if ev := v.(expireValue); !ev.isExpire(now) && !ev.Tombstone {
 			if len(prefix) != 0 && !strings.HasPrefix(k, prefix) {
 				return true
 			}
@@ -194,60 +532,325 @@ func (mem *MemCache) Keys(prefix string) Keys {
 	return &keys
 }
 
-// Size
+// Size 不包含墓碑(Tombstone)
 func (mem *MemCache) Size() int64 {
-	return mem.store.Size()
+	return mem.store.Size() - atomic.LoadInt64(&mem.tombstones)
+}
+
+// stored Store 中存在 key 且不是墓碑，包含已过期的
+func (mem *MemCache) stored(key string) bool {
+	v, ok := mem.store.Load(key)
+	return ok && !v.(expireValue).Tombstone
+}
+
+// Cost 当前存储内容的总成本，未设置 Sizer 和 LimitBytes 时与 Size 一致
+func (mem *MemCache) Cost() int64 {
+	return atomic.LoadInt64(&mem.cost)
 }
 
-// FlushAll 清空所有数据
+// FlushAll 清空所有数据，注册了回调时，会先遍历一次用于通知
 func (mem *MemCache) FlushAll() {
+	var flushed map[string]interface{}
+	if mem.hasCallbacks() {
+		flushed = make(map[string]interface{}, mem.Size())
+		mem.store.Range(func(k string, v interface{}) bool {
+			if ev := v.(expireValue); !ev.Tombstone {
+				flushed[k] = ev.Value
+			}
+			return true
+		})
+	}
+
 	mem.store.Flush()
+	atomic.StoreInt64(&mem.cost, 0)
+	atomic.StoreInt64(&mem.tombstones, 0)
+	atomic.StoreInt64(&mem.pinned, 0)
+	mem.flushNamespaces()
+	if mem.policy != nil {
+		mem.policy.Flush()
+	}
+	if mem.expiry != nil {
+		mem.expiry.flush()
+	}
+	if mem.ttlKeys != nil {
+		mem.ttlKeys.flush()
+	}
+	if mem.refresher != nil {
+		mem.refresher.flush()
+	}
+
+	for k, v := range flushed {
+		mem.notify(k, v, RemovalFlushed)
+	}
 }
 
-// Close
-func (mem *MemCache) Close() { mem.exit <- 1 }
+// Close 停止后台任务，可以重复调用
+func (mem *MemCache) Close() {
+	mem.closeOnce.Do(func() {
+		close(mem.exit)
+		if mem.refresher != nil {
+			mem.refresher.stop()
+		}
+		if mem.behind != nil {
+			<-mem.behind.done
+			mem.behind.drain()
+		}
+	})
+}
 
+// getValue 开启 BackendReadThrough 时，未命中从 Backend 加载
 func (mem *MemCache) getValue(key string) (expireValue, bool) {
+	ev, ok := mem.getCached(key)
+	if !ok && mem.backendMode&BackendReadThrough != 0 {
+		return mem.readThrough(key)
+	}
+	return ev, ok
+}
+
+func (mem *MemCache) getCached(key string) (expireValue, bool) {
 	v, ok := mem.store.Load(key)
+	return mem.accessValue(key, v, ok, mem.now())
+}
+
+// accessValue 检查从 Store 读取的值，更新统计、滑动过期和淘汰策略，MGet 复用
+func (mem *MemCache) accessValue(key string, v interface{}, ok bool, now int64) (expireValue, bool) {
 	if !ok {
+		atomic.AddInt64(&mem.misses, 1)
 		return expireValue{}, ok
 	}
 
 	ev := v.(expireValue)
-	if ev.isExpire(time.Now().Unix()) {
-		mem.store.Delete(key)
+	if ev.isExpire(now) {
+		atomic.AddInt64(&mem.misses, 1)
+		mem.removeExpired(key, now)
+		return expireValue{}, false
+	}
+	if ev.Tombstone {
+		atomic.AddInt64(&mem.misses, 1)
 		return expireValue{}, false
 	}
 
+	if ev.Idle > 0 {
+		ev, ok = mem.updateValue(key, func(ev *expireValue) bool {
+			return ev.slide(now)
+		})
+		if !ok {
+			atomic.AddInt64(&mem.misses, 1)
+			return expireValue{}, false
+		}
+	}
+
+	atomic.AddInt64(&mem.hits, 1)
+	if mem.policy != nil {
+		mem.policy.Access(key)
+	}
+	mem.needRefresh(key, ev, now)
+
 	return ev, true
 }
 
-func (mem *MemCache) setValue(key string, value interface{}, ttl int64) {
-	if ttl == 0 {
-		return
+// setValue Store 写入失败时(ByteStore 编码失败或 entry 过大)返回错误，不更新计数
+func (mem *MemCache) setValue(key string, ev expireValue) error {
+	// Swap 拿到被覆盖的值，准确计数当前容量
+	lock := mem.keyLock(key)
+	lock.Lock()
+	v, loaded, err := storeSwap(mem.store, key, ev)
+	lock.Unlock()
+	if err != nil {
+		return err
 	}
-	ev := expireValue{
-		Value: value,
+
+	mem.afterSet(key, ev, v, loaded)
+	return nil
+}
+
+// afterSet 写入 Store 后更新容量计数、索引和淘汰策略，覆盖时通知回调
+// 墓碑不计入数量，不参与淘汰，覆盖墓碑时不通知回调
+func (mem *MemCache) afterSet(key string, ev expireValue, v interface{}, loaded bool) {
+	tombstones := boolToInt64(ev.Tombstone)
+	size, cost, pinned := 1-tombstones, ev.Cost, boolToInt64(ev.Pinned)
+	var prev expireValue
+	if loaded {
+		prev = v.(expireValue)
+		tombstones -= boolToInt64(prev.Tombstone)
+		size -= 1 - boolToInt64(prev.Tombstone)
+		cost -= prev.Cost
+		pinned -= boolToInt64(prev.Pinned)
+	}
+	atomic.AddInt64(&mem.tombstones, tombstones)
+	atomic.AddInt64(&mem.cost, cost)
+	atomic.AddInt64(&mem.pinned, pinned)
+	mem.accountNamespace(key, size, cost, pinned)
+	mem.indexExpire(key, ev)
+	if mem.refresher != nil && ev.RefreshAt == 0 {
+		// 不是 loader 加载的值，不再刷新
+		mem.refresher.forget(key)
+	}
+
+	if mem.policy != nil {
+		switch {
+		case ev.Pinned || ev.Tombstone:
+			// 固定的 key 和墓碑不参与淘汰
+			mem.policy.Delete(key)
+		case loaded && !prev.Pinned && !prev.Tombstone:
+			mem.policy.Access(key)
+		default:
+			mem.policy.Insert(key)
+		}
+		mem.evict()
 	}
-	ev.ttl(ttl)
 
-	// LoadOrStore 为了准确计数当前容量
-	mem.store.LoadOrStore(key, ev)
+	if loaded && !prev.Tombstone {
+		mem.notify(key, prev.Value, RemovalReplaced)
+	}
+}
+
+// updateValue 在 key 锁内读取、修改并写回未过期的值，fn 返回 false 时不写回
+// 不存在、已过期或是不存在的标记(Tombstone)时返回 false
+func (mem *MemCache) updateValue(key string, fn func(ev *expireValue) bool) (expireValue, bool) {
+	lock := mem.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	v, ok := mem.store.Load(key)
+	if !ok {
+		return expireValue{}, false
+	}
+	ev := v.(expireValue)
+	if ev.isExpire(mem.now()) || ev.Tombstone {
+		return expireValue{}, false
+	}
+	if fn(&ev) {
+		mem.store.Store(key, ev)
+	}
+	return ev, true
+}
+
+// removeValue 删除 key，并更新容量计数和淘汰策略，删除成功后通知回调
+func (mem *MemCache) removeValue(key string, reason RemovalReason) (expireValue, bool) {
+	return mem.removeIf(key, reason, nil)
+}
+
+// removeExpired 只在 key 已过期时删除，避免删除并发写入的新值
+// 开启 StaleGrace 时，过期的 key 保留到宽限期结束
+func (mem *MemCache) removeExpired(key string, now int64) bool {
+	now -= int64(mem.staleGrace)
+	_, ok := mem.removeIf(key, RemovalExpired, func(ev expireValue) bool {
+		return ev.isExpire(now)
+	})
+	return ok
+}
+
+// removeIf cond 不为 nil 时，在 key 锁内检查当前值满足条件才删除
+func (mem *MemCache) removeIf(key string, reason RemovalReason, cond func(ev expireValue) bool) (expireValue, bool) {
+	lock := mem.keyLock(key)
+	lock.Lock()
+	if cond != nil {
+		if v, ok := mem.store.Load(key); ok && !cond(v.(expireValue)) {
+			lock.Unlock()
+			return expireValue{}, false
+		}
+	}
+	v, ok := storeLoadAndDelete(mem.store, key)
+	lock.Unlock()
+
+	return mem.afterRemove(key, v, ok, reason)
+}
+
+// afterRemove 从 Store 删除后更新容量计数和淘汰策略，删除成功时通知回调
+func (mem *MemCache) afterRemove(key string, v interface{}, ok bool, reason RemovalReason) (expireValue, bool) {
+	if mem.policy != nil {
+		mem.policy.Delete(key)
+	}
+	if mem.expiry != nil {
+		mem.expiry.remove(key)
+	}
+	if mem.ttlKeys != nil {
+		mem.ttlKeys.remove(key)
+	}
+	if !ok {
+		return expireValue{}, false
+	}
+	ev := v.(expireValue)
+	if ev.Tombstone {
+		// 墓碑不计入统计，不通知回调
+		atomic.AddInt64(&mem.tombstones, -1)
+		atomic.AddInt64(&mem.cost, -ev.Cost)
+		return ev, true
+	}
+	pinned := boolToInt64(ev.Pinned)
+	atomic.AddInt64(&mem.cost, -ev.Cost)
+	atomic.AddInt64(&mem.pinned, -pinned)
+	switch reason {
+	case RemovalEvicted:
+		atomic.AddInt64(&mem.evictions, 1)
+	case RemovalExpired:
+		atomic.AddInt64(&mem.expired, 1)
+	}
+	mem.accountNamespace(key, -1, -ev.Cost, -pinned)
+	mem.removedNamespace(key, reason)
+	if mem.refresher != nil {
+		mem.refresher.forget(key)
+	}
+	mem.notify(key, ev.Value, reason)
+	return ev, true
+}
+
+// storeEvicted ByteStore 环形覆盖 key 后更新容量计数和淘汰策略
+func (mem *MemCache) storeEvicted(key string, v interface{}) {
+	mem.afterRemove(key, v, true, RemovalEvicted)
+}
+
+func (mem *MemCache) keyLock(key string) *sync.Mutex {
+	return &mem.locks[hashKey(key)%keyLockStripes]
+}
+
+// now 当前时间，纳秒
+func (mem *MemCache) now() int64 {
+	return mem.clock.Now().UnixNano()
+}
+
+// sizeof 估算 key value 占用的字节数，未设置 sizer 时为 1
+func (mem *MemCache) sizeof(key string, value interface{}) int64 {
+	if mem.sizer == nil {
+		return 1
+	}
+	return int64(len(key)) + mem.sizer(value)
+}
+
+// overLimit 是否超过 key 数量或内存预算
+func (mem *MemCache) overLimit() bool {
+	if mem.limitSize >= 0 && mem.Size() > mem.limitSize {
+		return true
+	}
+	return mem.limitBytes > 0 && mem.Cost() > mem.limitBytes
+}
+
+// evict 超过容量时，按淘汰策略删除 key
+func (mem *MemCache) evict() {
+	for mem.overLimit() {
+		key, ok := mem.policy.Evict()
+		if !ok {
+			return
+		}
+		mem.removeValue(key, RemovalEvicted)
+	}
 }
 
 // AutoCleanExpireKey 自动在一定时间内清理过期 key
 // 当设置了大量的 expire key 且通常只读取一次的情况下再建议使用。
 // interval 建议设置大一点，否则可能影响写入性能，建议设置 5-10 minute
+// 开启 Config.ExpireIndex 后只处理到期的 key，interval 可以设置为 1 second
 func (mem *MemCache) AutoCleanExpireKey(interval time.Duration) {
 	mem.once.Do(func() {
+		ticker := mem.clock.NewTicker(interval)
 		go func() {
-			ticker := time.NewTicker(interval)
 			for {
 				select {
 				case <-mem.exit:
 					ticker.Stop()
 					return
-				case <-ticker.C:
+				case <-ticker.Chan():
 					mem.expireClean()
 				}
 			}
@@ -255,22 +858,71 @@ func (mem *MemCache) AutoCleanExpireKey(interval time.Duration) {
 	})
 }
 
+// indexExpire 开启过期索引或主动过期时，记录 TTL key，固定的 key 不会过期，不记录
+func (mem *MemCache) indexExpire(key string, ev expireValue) {
+	if ev.Pinned || ev.Expire == -1 {
+		if mem.expiry != nil {
+			mem.expiry.remove(key)
+		}
+		if mem.ttlKeys != nil {
+			mem.ttlKeys.remove(key)
+		}
+		return
+	}
+	if mem.expiry != nil {
+		mem.expiry.add(key, ev.Expire)
+	}
+	if mem.ttlKeys != nil {
+		mem.ttlKeys.add(key)
+	}
+}
+
 // 在超量后，才执行此函数
 func (mem *MemCache) expireClean() int {
+	if mem.expiry != nil {
+		return mem.expireCleanIndex()
+	}
+
 	keys := make([]string, 0)
-	nowSec := time.Now().Unix()
+	now := mem.now()
 	mem.store.Range(func(k string, v interface{}) bool {
-		if v.(expireValue).isExpire(nowSec) {
+		if v.(expireValue).isExpire(now) {
 			keys = append(keys, k)
 		}
 		return true
 	})
 
+	count := 0
 	for _, key := range keys {
-		mem.store.Delete(key)
+		if mem.removeExpired(key, now) {
+			count++
+		}
 	}
 	// 删除数量
-	return len(keys)
+	return count
+}
+
+// expireCleanIndex 只检查索引中到期的 key，过期时间被延长的 key 重新加入索引
+func (mem *MemCache) expireCleanIndex() int {
+	count := 0
+	now := mem.now()
+	// 宽限期结束的 key 才清理
+	due := now - int64(mem.staleGrace)
+	for _, key := range mem.expiry.expired(due) {
+		v, ok := mem.store.Load(key)
+		if !ok {
+			continue
+		}
+		ev := v.(expireValue)
+		if !ev.isExpire(due) {
+			mem.indexExpire(key, ev)
+			continue
+		}
+		if mem.removeExpired(key, now) {
+			count++
+		}
+	}
+	return count
 }
 
 // GobRegister 注册自定义结构
@@ -282,23 +934,34 @@ func (mem *MemCache) GobRegister(v ...interface{}) {
 	}
 }
 
+// diskFormatVersion 落盘格式版本
+// 1 - map[string]expireValue，过期时间为秒
+// 2 - diskData，过期时间为纳秒
+const diskFormatVersion = 2
+
+type diskData struct {
+	Version int
+	Values  map[string]expireValue
+}
+
 // WriteToDisk 缓存内容写入磁盘，当缓存内容比较大时，不建议写入磁盘，比较耗费时间
 func (mem *MemCache) WriteToDisk() error {
 
 	data := bytes.Buffer{}
 	enc := gob.NewEncoder(&data)
 
-	nowSec := time.Now().Unix()
+	now := mem.now()
 	values := make(map[string]expireValue, mem.Size())
 	mem.store.Range(func(k string, v interface{}) bool {
 		value := v.(expireValue)
-		if !value.isExpire(nowSec) {
+		// 墓碑只在 NegativeTTL 内有效，不落盘
+		if !value.isExpire(now) && !value.Tombstone {
 			values[k] = value
 		}
 		return true
 	})
 	log.Printf("WriteToDisk: to save the %d keys,in progress JSON encoding\n", len(values))
-	err := enc.Encode(values)
+	err := enc.Encode(diskData{Version: diskFormatVersion, Values: values})
 	if err != nil {
 		return err
 	}
@@ -306,10 +969,29 @@ func (mem *MemCache) WriteToDisk() error {
 	return mem.disk.WriteToFile(data.Bytes())
 }
 
-// LoadFromDisk 从磁盘中读取缓存内容，过过滤掉已经过期的内容
-func (mem *MemCache) LoadFromDisk() error {
+// decodeDiskData 解码落盘数据，兼容版本 1 的秒级过期时间
+func decodeDiskData(data []byte) (map[string]expireValue, error) {
+	disk := diskData{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&disk)
+	if err == nil {
+		return disk.Values, nil
+	}
+
 	values := make(map[string]expireValue, 0)
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(&values) != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		if v.Expire != -1 {
+			v.Expire *= int64(time.Second)
+			values[k] = v
+		}
+	}
+	return values, nil
+}
 
+// LoadFromDisk 从磁盘中读取缓存内容，过过滤掉已经过期的内容
+func (mem *MemCache) LoadFromDisk() error {
 	data, err := mem.disk.ReadFromFile()
 	if err != nil {
 		return err
@@ -320,20 +1002,27 @@ func (mem *MemCache) LoadFromDisk() error {
 		return nil
 	}
 
-	store := bytes.Buffer{}
-	store.Write(data)
-	dec := gob.NewDecoder(&store)
-	err = dec.Decode(&values)
+	values, err := decodeDiskData(data)
 	if err != nil {
 		return err
 	}
 
-	nowSec := time.Now().Unix()
+	now := mem.now()
 
 	for k, v := range values {
-		if !v.isExpire(nowSec) {
-			// 没有过期再写入
-			mem.setValue(k, v.Value, v.surplusSec(nowSec))
+		if !v.isExpire(now) {
+			// 没有过期再写入，固定的 key 保持固定
+			if v.Cost == 0 {
+				v.Cost = mem.sizeof(k, v.Value)
+			}
+			// 同一时间写入的 key 加载后分散过期，滑动过期的 key 下次读取时重新计算
+			// 固定的 key 保持原过期时间，已过期的固定 key 取消固定后立即过期
+			if v.Expire != -1 && v.Idle == 0 && !v.Pinned && v.Expire > now {
+				v.ttl(mem.jitter.spread(time.Duration(v.Expire-now), mem.ttlJitter), now)
+			}
+			if err := mem.setValue(k, v); err != nil {
+				log.Printf("LoadFromDisk: set key %s error, %v\n", k, err)
+			}
 		}
 	}
 
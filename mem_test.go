@@ -1,7 +1,12 @@
 package gocache
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -28,7 +33,8 @@ func TestMemCacheImpl_SetAndGet(t *testing.T) {
 }
 
 func TestMemCacheImpl_SetAndGetExpire(t *testing.T) {
-	cache := NewSyncMapCache()
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
 
 	err := cache.SetWithExpire("set", "1", 3)
 	if err != nil {
@@ -47,7 +53,7 @@ func TestMemCacheImpl_SetAndGetExpire(t *testing.T) {
 		return
 	}
 
-	time.Sleep(3 * time.Second)
+	clock.Advance(3 * time.Second)
 
 	_, ok = cache.Get("set")
 	if ok {
@@ -55,6 +61,124 @@ func TestMemCacheImpl_SetAndGetExpire(t *testing.T) {
 	}
 }
 
+func TestMemCacheImpl_SetAndGetTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewRWMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
+
+	err := cache.SetWithTTL("set", "1", 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	_, ttl, ok := cache.GetWithTTL("set")
+	if !ok {
+		t.Fatal("not exists")
+		return
+	}
+	if ttl != 200*time.Millisecond {
+		t.Fatal("ttl error", ttl)
+		return
+	}
+
+	_ = cache.SetWithTTL("forever", "2", NoExpiration)
+	_, ttl, _ = cache.GetWithTTL("forever")
+	if ttl != NoExpiration {
+		t.Fatal("ttl should NoExpiration", ttl)
+		return
+	}
+
+	clock.Advance(199 * time.Millisecond)
+	if _, ok = cache.Get("set"); !ok {
+		t.Fatal("key should not expired")
+		return
+	}
+	clock.Advance(time.Millisecond)
+
+	_, ok = cache.Get("set")
+	if ok {
+		t.Fatal("key should expired")
+	}
+}
+
+func TestMemCacheImpl_LoadLegacyDiskFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// 版本 1 的落盘格式，过期时间为秒
+	values := map[string]expireValue{
+		"ttl":     {Value: "1", Expire: time.Now().Unix() + 10},
+		"forever": {Value: "2", Expire: -1},
+		"expired": {Value: "3", Expire: time.Now().Unix() - 10},
+	}
+	data := bytes.Buffer{}
+	if err := gob.NewEncoder(&data).Encode(values); err != nil {
+		t.Fatal(err)
+		return
+	}
+	filename := filepath.Join(dir, "cache.gob")
+	if err := ioutil.WriteFile(filename, data.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Filename: filename})
+	if err := cache.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if cache.Size() != 2 {
+		t.Fatal("size error ", cache.Size())
+		return
+	}
+	_, ttl, ok := cache.GetWithTTL("ttl")
+	if !ok || ttl <= 8*time.Second || ttl > 10*time.Second {
+		t.Fatal("legacy ttl error ", ttl)
+		return
+	}
+	_, ttl, ok = cache.GetWithTTL("forever")
+	if !ok || ttl != NoExpiration {
+		t.Fatal("legacy forever error ", ttl)
+		return
+	}
+}
+
+func TestMemCacheImpl_SetWithIdleTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
+
+	_ = cache.SetWithIdleTimeout("session", "1", 100*time.Millisecond, 0)
+	_ = cache.SetWithIdleTimeout("limited", "2", 100*time.Millisecond, 150*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(60 * time.Millisecond)
+		if _, ok := cache.Get("session"); !ok {
+			t.Fatal("session should extended by get")
+			return
+		}
+	}
+	// 超过最长有效期限，读取不再延长
+	if _, ok := cache.Get("limited"); ok {
+		t.Fatal("limited should expired after max lifetime")
+		return
+	}
+
+	_, ttl, ok := cache.GetWithTTL("session")
+	if !ok || ttl != 100*time.Millisecond {
+		t.Fatal("session ttl error ", ttl)
+		return
+	}
+	clock.Advance(100 * time.Millisecond)
+	if _, ok := cache.Get("session"); ok {
+		t.Fatal("session should expired after idle")
+		return
+	}
+}
+
 func TestMemCacheImpl_Delete(t *testing.T) {
 	cache := NewSyncMapCache()
 
@@ -74,7 +198,8 @@ func TestMemCacheImpl_Delete(t *testing.T) {
 }
 
 func TestMemCacheImpl_Keys(t *testing.T) {
-	cache := NewSyncMapCache()
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
 
 	for i := 0; i < 10; i++ {
 		if i%2 == 0 {
@@ -106,7 +231,7 @@ func TestMemCacheImpl_Keys(t *testing.T) {
 		}
 	}
 	// 删除掉过期的key
-	time.Sleep(3 * time.Second)
+	clock.Advance(time.Second)
 
 	keys = cache.Keys("")
 	if keys.Size() != 5 {
@@ -161,10 +286,287 @@ func TestMemCacheImpl_Capacity(t *testing.T) {
 	}
 }
 
-func TestMemCacheImpl_AutoCleanExpireKey(t *testing.T) {
+func TestMemCacheImpl_EvictLRU(t *testing.T) {
+	config := Config{
+		LimitSize: 3,
+		Evict:     EvictLRU,
+	}
+	for _, cache := range []*MemCache{NewSyncMapCacheWithConfig(config), NewRWMapCacheWithConfig(config), NewShardedMapCacheWithConfig(config)} {
+		for i := 0; i < 3; i++ {
+			if err := cache.Set(fmt.Sprintf("%d", i), i); err != nil {
+				t.Fatal(err)
+				return
+			}
+		}
+		// 访问 0，最久未使用的变为 1
+		if _, ok := cache.Get("0"); !ok {
+			t.Fatal("0 should exists")
+			return
+		}
+
+		if err := cache.Set("3", 3); err != nil {
+			t.Fatal("evict mode should not return error ", err)
+			return
+		}
+		if cache.Size() != 3 {
+			t.Fatal("size error ", cache.Size())
+			return
+		}
+		if _, ok := cache.Get("1"); ok {
+			t.Fatal("1 should evicted")
+			return
+		}
+		for _, key := range []string{"0", "2", "3"} {
+			if _, ok := cache.Get(key); !ok {
+				t.Fatal("key should exists ", key)
+				return
+			}
+		}
+	}
+}
+
+func TestMemCacheImpl_LimitBytes(t *testing.T) {
+	value := string(make([]byte, 100))
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:  -1,
+		LimitBytes: 250,
+	})
+
+	for i := 0; i < 3; i++ {
+		err := cache.Set(fmt.Sprintf("%d", i), value)
+		if err != nil && i != 2 {
+			t.Fatal("0-1 error ", err)
+			return
+		} else if i == 2 && err != ErrOverLimitBytes {
+			t.Fatal("over limit bytes ", err)
+			return
+		}
+	}
+	// 覆盖写入不额外占用预算
+	if err := cache.Set("0", value); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if err := cache.Set("big", string(make([]byte, 300))); err != ErrOverLimitBytes {
+		t.Fatal("big value should over limit ", err)
+		return
+	}
+
+	cache = NewSyncMapCacheWithConfig(Config{
+		LimitSize:  -1,
+		LimitBytes: 250,
+		Evict:      EvictLRU,
+	})
+	for i := 0; i < 10; i++ {
+		if err := cache.Set(fmt.Sprintf("%d", i), value); err != nil {
+			t.Fatal(err)
+			return
+		}
+	}
+	if cache.Size() != 2 {
+		t.Fatal("evict by bytes error ", cache.Size())
+		return
+	}
+	if cache.Cost() != 202 {
+		t.Fatal("cost error ", cache.Cost())
+		return
+	}
+	cache.Delete("9")
+	if cache.Cost() != 101 {
+		t.Fatal("cost error ", cache.Cost())
+		return
+	}
+}
+
+func TestMemCacheImpl_SetWithCost(t *testing.T) {
 	cache := NewSyncMapCache()
+	_ = cache.Set("a", "1")
+	_ = cache.Set("b", "2")
+	if cache.Cost() != cache.Size() {
+		t.Fatal("default cost should equal size ", cache.Cost())
+		return
+	}
+
+	cache = NewRWMapCacheWithConfig(Config{
+		LimitSize:  -1,
+		LimitBytes: 10,
+		Evict:      EvictLRU,
+	})
+	if err := cache.SetWithCost("report", "r", 6, -1); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if err := cache.SetWithCost("cheap", "c", 1, -1); err != nil {
+		t.Fatal(err)
+		return
+	}
+	cache.Get("report")
+	if err := cache.SetWithCost("other", "o", 4, -1); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if _, ok := cache.Get("cheap"); ok {
+		t.Fatal("cheap should evicted")
+		return
+	}
+	if cache.Cost() != 10 {
+		t.Fatal("cost error ", cache.Cost())
+		return
+	}
+	if err := cache.SetWithCost("huge", "h", 11, -1); err != ErrOverLimitBytes {
+		t.Fatal("huge should over limit ", err)
+		return
+	}
+}
+
+func TestMemCacheImpl_OnRemoved(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize: 1,
+		Evict:     EvictLRU,
+	})
+
+	reasons := make(map[string]RemovalReason)
+	values := make(map[string]interface{})
+	cache.OnRemoved(func(key string, value interface{}, reason RemovalReason) {
+		// 回调中可以再次操作缓存
+		cache.Get(key)
+		reasons[key] = reason
+		values[key] = value
+	})
+	evicted := 0
+	cache.OnEvicted(func(key string, value interface{}) {
+		evicted++
+	})
+
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+	if reasons["a"] != RemovalEvicted || values["a"].(int) != 1 || evicted != 1 {
+		t.Fatal("evicted callback error ", reasons["a"])
+		return
+	}
+
+	_ = cache.Set("b", 3)
+	if reasons["b"] != RemovalReplaced || values["b"].(int) != 2 {
+		t.Fatal("replaced callback error ", reasons["b"], values["b"])
+		return
+	}
+
+	cache.Delete("b")
+	if reasons["b"] != RemovalDeleted || values["b"].(int) != 3 {
+		t.Fatal("deleted callback error ", reasons["b"])
+		return
+	}
+
+	_, _, _ = storeSwap(cache.store, "e", expireValue{Value: 4, Expire: time.Now().UnixNano() - 1})
+	if _, ok := cache.Get("e"); ok {
+		t.Fatal("e should expired")
+		return
+	}
+	if reasons["e"] != RemovalExpired {
+		t.Fatal("expired callback error ", reasons["e"])
+		return
+	}
+
+	_ = cache.Set("f", 5)
+	cache.FlushAll()
+	if reasons["f"] != RemovalFlushed || reasons["f"].String() != "flushed" {
+		t.Fatal("flushed callback error ", reasons["f"])
+		return
+	}
+	if evicted != 1 {
+		t.Fatal("OnEvicted should only called on evict ", evicted)
+		return
+	}
+}
 
-	cache.AutoCleanExpireKey(10 * time.Millisecond)
+func TestMemCacheImpl_Pin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{
+		LimitSize: 2,
+		Evict:     EvictLRU,
+		Filename:  filepath.Join(dir, "cache.gob"),
+	}
+	cache := NewSyncMapCacheWithConfig(config)
+
+	if err := cache.SetPinned("config", "1"); err != nil {
+		t.Fatal(err)
+		return
+	}
+	_ = cache.SetWithExpire("ttl", "2", 10)
+	if !cache.Pin("ttl") {
+		t.Fatal("pin error")
+		return
+	}
+	if cache.Pin("not-exists") {
+		t.Fatal("pin not exists key should false")
+		return
+	}
+	// 固定的 key 不会被淘汰，新 key 写入后只能淘汰自己
+	_ = cache.Set("a", "3")
+	if _, ok := cache.Get("config"); !ok {
+		t.Fatal("pinned key should not evicted")
+		return
+	}
+	if _, ok := cache.Get("ttl"); !ok {
+		t.Fatal("pinned key should not evicted")
+		return
+	}
+	// 覆盖写入保持固定
+	_ = cache.Set("config", "4")
+
+	stats := cache.Stats()
+	if stats.Pinned != 2 || stats.Evictions != 1 {
+		t.Fatal("stats error ", stats)
+		return
+	}
+
+	if err := cache.WriteToDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	loaded := NewSyncMapCacheWithConfig(config)
+	if err := loaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if loaded.Stats().Pinned != 2 {
+		t.Fatal("pinned should kept through disk ", loaded.Stats())
+		return
+	}
+
+	if !loaded.Unpin("ttl") {
+		t.Fatal("unpin error")
+		return
+	}
+	_ = loaded.Set("b", "5")
+	if _, ok := loaded.Get("ttl"); ok {
+		t.Fatal("unpinned key should evicted")
+		return
+	}
+	v, ok := loaded.Get("config")
+	if !ok || v.(string) != "4" {
+		t.Fatal("pinned key value error ", v)
+		return
+	}
+	loaded.Delete("config")
+	if loaded.Stats().Pinned != 0 {
+		t.Fatal("pinned count error ", loaded.Stats())
+		return
+	}
+}
+
+func TestMemCacheImpl_AutoCleanExpireKey(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{LimitSize: -1, Clock: clock})
+
+	cache.AutoCleanExpireKey(time.Minute)
+	defer cache.Close()
 
 	err := cache.Set("set", "1")
 	if err != nil {
@@ -184,7 +586,15 @@ func TestMemCacheImpl_AutoCleanExpireKey(t *testing.T) {
 		return
 	}
 
-	time.Sleep(3 * time.Second)
+	// 过期但还没有清理
+	clock.Advance(2 * time.Second)
+	if cache.Size() != 2 {
+		t.Fatal("should not clean before tick")
+		return
+	}
+
+	clock.Advance(time.Minute)
+	waitFor(func() bool { return cache.Size() == 1 })
 
 	size = cache.Size()
 	if size != 1 {
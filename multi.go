@@ -0,0 +1,213 @@
+package gocache
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// lockKeys 按分段锁序号从小到大加锁，批量操作只对每个分段加一次锁，返回解锁函数
+func (mem *MemCache) lockKeys(keys []string) func() {
+	seen := make(map[uint64]struct{}, len(keys))
+	stripes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		i := hashKey(key) % keyLockStripes
+		if _, ok := seen[i]; !ok {
+			seen[i] = struct{}{}
+			stripes = append(stripes, int(i))
+		}
+	}
+	sort.Ints(stripes)
+	for _, i := range stripes {
+		mem.locks[i].Lock()
+	}
+	return func() {
+		for _, i := range stripes {
+			mem.locks[i].Unlock()
+		}
+	}
+}
+
+// MGet 批量读取，只返回存在的 key，对 Store 只调用一次 MLoad
+// 开启 BackendReadThrough 时，未命中的 key 一次从 Backend GetMany 加载
+func (mem *MemCache) MGet(keys []string) map[string]interface{} {
+	raw := mem.store.MLoad(keys)
+	now := mem.now()
+	values := make(map[string]interface{}, len(raw))
+	misses := make([]string, 0)
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		v, ok := raw[key]
+		if ev, ok := mem.accessValue(key, v, ok, now); ok {
+			values[key] = ev.Value
+		} else {
+			misses = append(misses, key)
+		}
+	}
+
+	if len(misses) > 0 && mem.backendMode&BackendReadThrough != 0 {
+		mem.readThroughMany(misses, values)
+	}
+	return values
+}
+
+// readThroughMany 从 Backend 批量加载，不存在的 key 按 NegativeTTL 缓存，失败时只记录日志
+func (mem *MemCache) readThroughMany(keys []string, values map[string]interface{}) {
+	misses := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if ev, ok := mem.peek(key); ok && ev.Tombstone {
+			continue
+		}
+		misses = append(misses, key)
+	}
+	if len(misses) == 0 {
+		return
+	}
+
+	loaded, err := mem.backend.GetMany(context.Background(), misses)
+	if err != nil {
+		log.Printf("MGet: backend get many error, %v\n", err)
+		return
+	}
+	for _, key := range misses {
+		value, ok := loaded[key]
+		if !ok {
+			_ = mem.setNotFound(key)
+			continue
+		}
+		values[key] = value
+		_ = mem.set(key, mem.ttlValue(key, value, mem.backendTTL))
+	}
+}
+
+// MSet 批量写入，ttl 同 SetWithTTL，对 Store 只调用一次 MStore
+// 未设置淘汰策略时，LimitSize 按整批新增的 key 检查，超过时全部不写入，返回 ErrKeysOverLimitSize
+// 写入 Backend 时同 SetWithTTL，缓存拒绝写入不返回错误，删除缓存中的旧值
+func (mem *MemCache) MSet(values map[string]interface{}, ttl time.Duration) error {
+	if ttl == 0 || len(values) == 0 {
+		return nil
+	}
+	if mem.backendMode&BackendWriteThrough != 0 {
+		if err := mem.backend.PutMany(context.Background(), values); err != nil {
+			return err
+		}
+	}
+
+	err := mem.mset(values, ttl)
+	if !mem.writesBackend() {
+		return err
+	}
+	if err != nil {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		mem.mremove(keys, RemovalReplaced)
+	}
+	if mem.behind != nil {
+		for key, value := range values {
+			mem.behind.put(key, value)
+		}
+	}
+	return nil
+}
+
+// mset 批量写入缓存，不写入 Backend
+func (mem *MemCache) mset(values map[string]interface{}, ttl time.Duration) error {
+	keys := make([]string, 0, len(values))
+	evs := make(map[string]interface{}, len(values))
+	cost := int64(0)
+	for key, value := range values {
+		ev := mem.ttlValue(key, value, ttl)
+		if mem.limitBytes > 0 && ev.Cost > mem.limitBytes {
+			return ErrOverLimitBytes
+		}
+		cost += ev.Cost
+		keys = append(keys, key)
+		evs[key] = ev
+	}
+
+	unlock := mem.lockKeys(keys)
+	// 覆盖固定的 key 时保持固定，未设置淘汰策略时计算成本差值和覆盖的墓碑数量
+	tombstones := atomic.LoadInt64(&mem.tombstones)
+	if atomic.LoadInt64(&mem.pinned) > 0 || (mem.policy == nil && (mem.limitBytes > 0 || (mem.limitSize >= 0 && tombstones > 0))) {
+		for key, v := range mem.store.MLoad(keys) {
+			old := v.(expireValue)
+			if old.Tombstone {
+				tombstones--
+			}
+			cost -= old.Cost
+			if old.Pinned {
+				ev := evs[key].(expireValue)
+				ev.Pinned = true
+				evs[key] = ev
+			}
+		}
+	}
+	if mem.limitBytes > 0 && mem.policy == nil && mem.Cost()+cost > mem.limitBytes {
+		unlock()
+		return ErrOverLimitBytes
+	}
+	limit := int64(-1)
+	if mem.policy == nil && mem.limitSize >= 0 {
+		// Store 的数量包含墓碑，覆盖的墓碑变为 key 后计入 LimitSize
+		limit = mem.limitSize + tombstones
+	}
+	previous, ok, err := storeMStore(mem.store, evs, limit)
+	unlock()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrKeysOverLimitSize
+	}
+
+	for key, ev := range evs {
+		v, loaded := previous[key]
+		mem.afterSet(key, ev.(expireValue), v, loaded)
+	}
+	return nil
+}
+
+// MDelete 批量删除，对 Store 只调用一次 MDelete
+func (mem *MemCache) MDelete(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if mem.backendMode&BackendWriteThrough != 0 {
+		if err := mem.backend.DeleteMany(context.Background(), keys); err != nil {
+			log.Printf("MDelete: backend delete many error, %v\n", err)
+		}
+	}
+	if mem.behind != nil {
+		for _, key := range keys {
+			mem.behind.delete(key)
+		}
+	}
+
+	mem.mremove(keys, RemovalDeleted)
+}
+
+// mremove 批量删除缓存，不写入 Backend
+func (mem *MemCache) mremove(keys []string, reason RemovalReason) {
+	unlock := mem.lockKeys(keys)
+	removed := mem.store.MDelete(keys)
+	unlock()
+
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		v, ok := removed[key]
+		mem.afterRemove(key, v, ok, reason)
+	}
+}
@@ -0,0 +1,146 @@
+package gocache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_MGetMSetMDelete(t *testing.T) {
+	for _, cache := range []*MemCache{NewSyncMapCache(), NewRWMapCache(), NewShardedMapCache(), NewByteStoreCache(1 << 20)} {
+		values := make(map[string]interface{})
+		for i := 0; i < 100; i++ {
+			values[fmt.Sprintf("%d", i)] = i
+		}
+		if err := cache.MSet(values, time.Minute); err != nil {
+			t.Fatal(err)
+			return
+		}
+		if cache.Size() != 100 {
+			t.Fatal("mset size error ", cache.Size())
+			return
+		}
+		if ttl, ok := cache.TTL("1"); !ok || ttl <= 59*time.Second {
+			t.Fatal("mset ttl error ", ttl)
+			return
+		}
+
+		got := cache.MGet([]string{"1", "2", "1", "not-exists"})
+		if len(got) != 2 || got["1"].(int) != 1 || got["2"].(int) != 2 {
+			t.Fatal("mget error ", got)
+			return
+		}
+		if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+			t.Fatal("mget stats error ", stats)
+			return
+		}
+
+		cache.MDelete([]string{"1", "2", "not-exists"})
+		if cache.Size() != 98 || len(cache.MGet([]string{"1", "2"})) != 0 {
+			t.Fatal("mdelete error ", cache.Size())
+			return
+		}
+	}
+}
+
+func TestMemCacheImpl_MSetLimitSize(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{LimitSize: 3})
+	_ = cache.Set("a", 1)
+
+	removed := 0
+	cache.OnRemoved(func(key string, value interface{}, reason RemovalReason) {
+		removed++
+	})
+	// 整批检查，超过时全部不写入
+	err := cache.MSet(map[string]interface{}{"a": 11, "b": 2, "c": 3, "d": 4}, NoExpiration)
+	if err != ErrKeysOverLimitSize {
+		t.Fatal("should over limit ", err)
+		return
+	}
+	if cache.Size() != 1 || removed != 0 {
+		t.Fatal("over limit should not write ", cache.Size())
+		return
+	}
+
+	if err := cache.MSet(map[string]interface{}{"a": 11, "b": 2, "c": 3}, NoExpiration); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if v, _ := cache.Get("a"); v.(int) != 11 || cache.Size() != 3 || removed != 1 {
+		t.Fatal("mset replace error ", v, cache.Size(), removed)
+		return
+	}
+
+	// 淘汰策略
+	lru := NewShardedMapCacheWithConfig(Config{LimitSize: 2, Evict: EvictLRU})
+	_ = lru.SetPinned("pinned", 0)
+	_ = lru.MSet(map[string]interface{}{"pinned": 1, "b": 2, "c": 3}, NoExpiration)
+	if lru.Size() != 2 || lru.Stats().Pinned != 1 {
+		t.Fatal("mset evict error ", lru.Size(), lru.Stats())
+		return
+	}
+	if v, ok := lru.Get("pinned"); !ok || v.(int) != 1 {
+		t.Fatal("pinned should keep ", v)
+		return
+	}
+}
+
+func TestMemCacheImpl_MultiBackend(t *testing.T) {
+	backend := NewMapBackend()
+	_ = backend.Put(context.Background(), "db", "1")
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Backend:     backend,
+		BackendMode: BackendReadThrough | BackendWriteThrough,
+	})
+
+	got := cache.MGet([]string{"db", "missing"})
+	if len(got) != 1 || got["db"].(string) != "1" {
+		t.Fatal("mget read through error ", got)
+		return
+	}
+	if _, ok := cache.TTL("db"); !ok {
+		t.Fatal("read through should cached")
+		return
+	}
+
+	_ = cache.MSet(map[string]interface{}{"a": 1, "b": 2}, time.Minute)
+	if backend.Len() != 3 {
+		t.Fatal("mset write through error ", backend.Len())
+		return
+	}
+	cache.MDelete([]string{"a", "db"})
+	if backend.Len() != 1 || cache.Size() != 1 {
+		t.Fatal("mdelete write through error ", backend.Len(), cache.Size())
+		return
+	}
+}
+
+func TestNamespace_Multi(t *testing.T) {
+	cache := NewSyncMapCache()
+	ns := cache.Namespace("team", NamespaceConfig{LimitSize: 2})
+
+	if err := ns.MSet(map[string]interface{}{"a": 1, "b": 2, "c": 3}, NoExpiration); err != ErrKeysOverLimitSize {
+		t.Fatal("namespace should over limit ", err)
+		return
+	}
+	if err := ns.MSet(map[string]interface{}{"a": 1, "b": 2}, NoExpiration); err != nil {
+		t.Fatal(err)
+		return
+	}
+	got := ns.MGet([]string{"a", "b", "c"})
+	if len(got) != 2 || got["a"].(int) != 1 {
+		t.Fatal("namespace mget error ", got)
+		return
+	}
+	if stats := ns.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatal("namespace stats error ", stats)
+		return
+	}
+	ns.MDelete([]string{"a"})
+	if ns.Size() != 1 || cache.Size() != 1 {
+		t.Fatal("namespace mdelete error ", ns.Size())
+		return
+	}
+}
@@ -0,0 +1,280 @@
+package gocache
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// namespace 与 key 之间的分隔符，namespace 中的 key 在 Store 中存储为 name + nsSep + key
+const nsSep = "\x00"
+
+var _ Cache = (*Namespace)(nil)
+
+// NamespaceConfig namespace 配置
+type NamespaceConfig struct {
+	// key 数量上限，超过后写入报错 ErrKeysOverLimitSize， <= 0 - 不限制
+	LimitSize int64
+	// Set 使用的默认过期时间， <= 0 - 永久有效
+	DefaultTTL time.Duration
+}
+
+// Namespace MemCache 的一个视图，与 MemCache 共用 Store，拥有独立的容量、默认过期时间和统计
+// Keys、FlushAll、Size 只作用于当前 namespace 的 key
+type Namespace struct {
+	// 统计计数，atomic 操作，放在首位保证 32 位系统下 64 位对齐
+	size      int64
+	cost      int64
+	pinned    int64
+	hits      int64
+	misses    int64
+	evictions int64
+	expired   int64
+
+	mem        *MemCache
+	name       string
+	prefix     string
+	limitSize  int64
+	defaultTTL time.Duration
+}
+
+// Namespace 获取 namespace，不存在时按 config 创建，同名 namespace 返回同一个实例，配置以第一次创建为准
+// name 不能包含 "\x00"，需要在 LoadFromDisk 之前创建，否则加载的 key 不计入 namespace 的容量
+func (mem *MemCache) Namespace(name string, config NamespaceConfig) *Namespace {
+	if v, ok := mem.namespaces.Load(name); ok {
+		return v.(*Namespace)
+	}
+
+	ttl := NoExpiration
+	if config.DefaultTTL > 0 {
+		ttl = config.DefaultTTL
+	}
+	ns := &Namespace{
+		mem:        mem,
+		name:       name,
+		prefix:     name + nsSep,
+		limitSize:  config.LimitSize,
+		defaultTTL: ttl,
+	}
+	v, _ := mem.namespaces.LoadOrStore(name, ns)
+	return v.(*Namespace)
+}
+
+// namespaceOf key 所属的 namespace，不属于任何 namespace 返回 nil
+func (mem *MemCache) namespaceOf(key string) *Namespace {
+	i := strings.Index(key, nsSep)
+	if i < 0 {
+		return nil
+	}
+	v, ok := mem.namespaces.Load(key[:i])
+	if !ok {
+		return nil
+	}
+	return v.(*Namespace)
+}
+
+// accountNamespace 更新 key 所属 namespace 的计数
+func (mem *MemCache) accountNamespace(key string, size, cost, pinned int64) {
+	ns := mem.namespaceOf(key)
+	if ns == nil {
+		return
+	}
+	atomic.AddInt64(&ns.size, size)
+	atomic.AddInt64(&ns.cost, cost)
+	atomic.AddInt64(&ns.pinned, pinned)
+}
+
+func (mem *MemCache) removedNamespace(key string, reason RemovalReason) {
+	ns := mem.namespaceOf(key)
+	if ns == nil {
+		return
+	}
+	switch reason {
+	case RemovalEvicted:
+		atomic.AddInt64(&ns.evictions, 1)
+	case RemovalExpired:
+		atomic.AddInt64(&ns.expired, 1)
+	}
+}
+
+// flushNamespaces MemCache.FlushAll 后重置所有 namespace 的容量计数
+func (mem *MemCache) flushNamespaces() {
+	mem.namespaces.Range(func(k, v interface{}) bool {
+		ns := v.(*Namespace)
+		atomic.StoreInt64(&ns.size, 0)
+		atomic.StoreInt64(&ns.cost, 0)
+		atomic.StoreInt64(&ns.pinned, 0)
+		return true
+	})
+}
+
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+func (ns *Namespace) Get(key string) (value interface{}, exists bool) {
+	value, _, exists = ns.GetWithExpire(key)
+	return value, exists
+}
+
+func (ns *Namespace) GetWithExpire(key string) (value interface{}, ttl int64, exists bool) {
+	value, d, exists := ns.GetWithTTL(key)
+	if !exists {
+		return nil, 0, false
+	}
+	if d == NoExpiration {
+		return value, -1, true
+	}
+	return value, int64(d / time.Second), true
+}
+
+func (ns *Namespace) GetWithTTL(key string) (value interface{}, ttl time.Duration, exists bool) {
+	value, ttl, exists = ns.mem.GetWithTTL(ns.prefix + key)
+	if exists {
+		atomic.AddInt64(&ns.hits, 1)
+	} else {
+		atomic.AddInt64(&ns.misses, 1)
+	}
+	return value, ttl, exists
+}
+
+// Set 使用 namespace 的默认过期时间
+func (ns *Namespace) Set(key string, value interface{}) error {
+	return ns.SetWithTTL(key, value, ns.defaultTTL)
+}
+
+func (ns *Namespace) SetWithExpire(key string, value interface{}, ttl int64) error {
+	return ns.SetWithTTL(key, value, secondsToTTL(ttl))
+}
+
+func (ns *Namespace) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	key = ns.prefix + key
+	if ns.limitSize > 0 && ns.Size() >= ns.limitSize && !ns.mem.stored(key) {
+		return ErrKeysOverLimitSize
+	}
+	return ns.mem.SetWithTTL(key, value, ttl)
+}
+
+func (ns *Namespace) Expire(key string, ttl time.Duration) bool {
+	return ns.mem.Expire(ns.prefix+key, ttl)
+}
+
+func (ns *Namespace) ExpireAt(key string, at time.Time) bool {
+	return ns.mem.ExpireAt(ns.prefix+key, at)
+}
+
+func (ns *Namespace) Persist(key string) bool {
+	return ns.mem.Persist(ns.prefix + key)
+}
+
+func (ns *Namespace) TTL(key string) (time.Duration, bool) {
+	return ns.mem.TTL(ns.prefix + key)
+}
+
+func (ns *Namespace) Touch(key string) bool {
+	return ns.mem.Touch(ns.prefix + key)
+}
+
+// MGet 返回的 key 不包含 namespace 前缀
+func (ns *Namespace) MGet(keys []string) map[string]interface{} {
+	prefixed := make([]string, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			prefixed = append(prefixed, ns.prefix+key)
+		}
+	}
+	values := make(map[string]interface{}, len(prefixed))
+	for k, v := range ns.mem.MGet(prefixed) {
+		values[k[len(ns.prefix):]] = v
+	}
+	atomic.AddInt64(&ns.hits, int64(len(values)))
+	atomic.AddInt64(&ns.misses, int64(len(prefixed)-len(values)))
+	return values
+}
+
+// MSet namespace 的 LimitSize 按整批新增的 key 检查，超过时全部不写入
+func (ns *Namespace) MSet(values map[string]interface{}, ttl time.Duration) error {
+	prefixed := make(map[string]interface{}, len(values))
+	n := int64(0)
+	for key, value := range values {
+		key = ns.prefix + key
+		prefixed[key] = value
+		if !ns.mem.stored(key) {
+			n++
+		}
+	}
+	if ns.limitSize > 0 && ns.Size()+n > ns.limitSize {
+		return ErrKeysOverLimitSize
+	}
+	return ns.mem.MSet(prefixed, ttl)
+}
+
+func (ns *Namespace) MDelete(keys []string) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, ns.prefix+key)
+	}
+	ns.mem.MDelete(prefixed)
+}
+
+func (ns *Namespace) Keys(prefix string) Keys {
+	keys := ns.mem.Keys(ns.prefix + prefix).(*iKeys)
+	for i, k := range keys.keys {
+		keys.keys[i] = k[len(ns.prefix):]
+	}
+	return keys
+}
+
+func (ns *Namespace) Delete(key string) {
+	ns.mem.Delete(ns.prefix + key)
+}
+
+func (ns *Namespace) Size() int64 {
+	return atomic.LoadInt64(&ns.size)
+}
+
+// FlushAll 删除当前 namespace 的所有 key，需要遍历整个 Store
+func (ns *Namespace) FlushAll() {
+	keys := make([]string, 0, ns.Size())
+	ns.mem.store.Range(func(k string, v interface{}) bool {
+		if strings.HasPrefix(k, ns.prefix) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	for _, key := range keys {
+		ns.mem.removeValue(key, RemovalFlushed)
+	}
+}
+
+func (ns *Namespace) GobRegister(v ...interface{}) {
+	ns.mem.GobRegister(v...)
+}
+
+// WriteToDisk 与所属 MemCache 共用文件，写入所有 namespace 的数据
+func (ns *Namespace) WriteToDisk() error {
+	return ns.mem.WriteToDisk()
+}
+
+// LoadFromDisk 与所属 MemCache 共用文件，加载所有 namespace 的数据
+func (ns *Namespace) LoadFromDisk() error {
+	return ns.mem.LoadFromDisk()
+}
+
+// Close namespace 的生命周期由所属 MemCache 管理，不做任何操作
+func (ns *Namespace) Close() {}
+
+// Stats namespace 的统计
+func (ns *Namespace) Stats() Stats {
+	return Stats{
+		Size:      ns.Size(),
+		Pinned:    atomic.LoadInt64(&ns.pinned),
+		Cost:      atomic.LoadInt64(&ns.cost),
+		Hits:      atomic.LoadInt64(&ns.hits),
+		Misses:    atomic.LoadInt64(&ns.misses),
+		Evictions: atomic.LoadInt64(&ns.evictions),
+		Expired:   atomic.LoadInt64(&ns.expired),
+	}
+}
@@ -0,0 +1,93 @@
+package gocache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	cache := NewRWMapCache()
+	users := cache.Namespace("users", NamespaceConfig{
+		LimitSize:  3,
+		DefaultTTL: 10 * time.Second,
+	})
+	orders := cache.Namespace("orders", NamespaceConfig{})
+
+	if cache.Namespace("users", NamespaceConfig{}) != users {
+		t.Fatal("same name should return same namespace")
+		return
+	}
+
+	for i := 0; i < 4; i++ {
+		err := users.Set(fmt.Sprintf("%d", i), i)
+		if err != nil && i != 3 {
+			t.Fatal("0-2 error ", err)
+			return
+		} else if i == 3 && err != ErrKeysOverLimitSize {
+			t.Fatal("namespace over limit ", err)
+			return
+		}
+	}
+	// 已存在的 key 可以覆盖写入
+	if err := users.Set("0", 0); err != nil {
+		t.Fatal(err)
+		return
+	}
+	_ = orders.Set("0", "order")
+	_ = cache.Set("0", "default")
+
+	_, ttl, ok := users.GetWithExpire("0")
+	if !ok || ttl < 9 {
+		t.Fatal("default ttl error ", ttl)
+		return
+	}
+	v, ok := orders.Get("0")
+	if !ok || v.(string) != "order" {
+		t.Fatal("orders value error ", v)
+		return
+	}
+	v, ok = cache.Get("0")
+	if !ok || v.(string) != "default" {
+		t.Fatal("default value error ", v)
+		return
+	}
+
+	if users.Size() != 3 || orders.Size() != 1 || cache.Size() != 5 {
+		t.Fatal("size error ", users.Size(), orders.Size(), cache.Size())
+		return
+	}
+	keys := users.Keys("")
+	if keys.Size() != 3 {
+		t.Fatal("keys error ", keys.Size())
+		return
+	}
+	for _, key := range keys.Value() {
+		switch key {
+		case "0", "1", "2":
+		default:
+			t.Fatal("keys value error ", key)
+			return
+		}
+	}
+
+	users.Delete("1")
+	users.FlushAll()
+	if users.Size() != 0 || orders.Size() != 1 || cache.Size() != 2 {
+		t.Fatal("flush namespace error ", users.Size(), orders.Size(), cache.Size())
+		return
+	}
+
+	users.Get("0")
+	stats := users.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatal("namespace stats error ", stats)
+		return
+	}
+
+	cache.FlushAll()
+	if orders.Size() != 0 {
+		t.Fatal("flush all should reset namespace size ", orders.Size())
+		return
+	}
+}
@@ -0,0 +1,206 @@
+package gocache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_NegativeTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Clock:       clock,
+		NegativeTTL: 10 * time.Second,
+	})
+
+	calls := 0
+	notFound := func(ctx context.Context) (interface{}, time.Duration, error) {
+		calls++
+		return nil, 0, ErrNotFound
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetOrLoad(context.Background(), "id", notFound); err != ErrNotFound {
+			t.Fatal("should ErrNotFound ", err)
+			return
+		}
+	}
+	if calls != 1 {
+		t.Fatal("not found should cached ", calls)
+		return
+	}
+
+	if _, ok := cache.Get("id"); ok {
+		t.Fatal("tombstone Get should not exists")
+		return
+	}
+	if _, ok := cache.TTL("id"); ok || cache.Touch("id") || cache.Expire("id", time.Hour) {
+		t.Fatal("tombstone should not exists")
+		return
+	}
+	_ = cache.Set("other", 1)
+	if keys := cache.Keys(""); keys.Size() != 1 || keys.Value()[0] != "other" {
+		t.Fatal("tombstone should not in keys ", keys.Value())
+		return
+	}
+
+	// 过期后重新加载
+	clock.Advance(10 * time.Second)
+	v, err := cache.GetOrLoad(context.Background(), "id", func(ctx context.Context) (interface{}, time.Duration, error) {
+		calls++
+		return "1", time.Minute, nil
+	})
+	if err != nil || v.(string) != "1" || calls != 2 {
+		t.Fatal("should load after negative ttl ", v, err, calls)
+		return
+	}
+
+	// 未开启不缓存
+	cache = NewRWMapCache()
+	calls = 0
+	_, _ = cache.GetOrLoad(context.Background(), "id", notFound)
+	_, err = cache.GetOrLoad(context.Background(), "id", notFound)
+	if err != ErrNotFound || calls != 2 || cache.Size() != 0 {
+		t.Fatal("not found should not cached ", calls, cache.Size())
+		return
+	}
+}
+
+func TestMemCacheImpl_NegativeTTLBestEffort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{
+		LimitSize:   -1,
+		NegativeTTL: 10 * time.Second,
+		Filename:    filepath.Join(dir, "cache.gob"),
+	}
+	cache := NewRWMapCacheWithConfig(config)
+	notFound := func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, ErrNotFound
+	}
+	if _, err := cache.GetOrLoad(context.Background(), "id", notFound); err != ErrNotFound {
+		t.Fatal("should ErrNotFound ", err)
+		return
+	}
+
+	// key 超过分片大小，墓碑写入失败仍然返回 ErrNotFound
+	bs := NewByteStoreCacheWithConfig(0, Config{LimitSize: -1, NegativeTTL: 10 * time.Second})
+	long := string(make([]byte, 2*minShardBytes))
+	if _, err := bs.GetOrLoad(context.Background(), long, notFound); err != ErrNotFound {
+		t.Fatal("tombstone write error should ErrNotFound ", err)
+		return
+	}
+
+	// 墓碑不落盘
+	if err := cache.WriteToDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	loaded := NewRWMapCacheWithConfig(config)
+	if err := loaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+		return
+	}
+	if loaded.store.Size() != 0 {
+		t.Fatal("tombstone should not write to disk ", loaded.store.Size())
+		return
+	}
+}
+
+func TestMemCacheImpl_NegativeTTLCallback(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Clock:       clock,
+		NegativeTTL: 10 * time.Second,
+	})
+	removed := make([]string, 0)
+	cache.OnRemoved(func(key string, value interface{}, reason RemovalReason) {
+		removed = append(removed, key)
+	})
+	notFound := func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, ErrNotFound
+	}
+
+	_, _ = cache.GetOrLoad(context.Background(), "t", notFound)
+	_, _ = cache.GetOrLoad(context.Background(), "u", notFound)
+	if cache.Size() != 0 || cache.Cost() != 0 {
+		t.Fatal("tombstone should not count ", cache.Size(), cache.Cost())
+		return
+	}
+
+	// 覆盖墓碑和墓碑过期都不通知回调，不计入统计
+	_ = cache.Set("t", 1)
+	clock.Advance(10 * time.Second)
+	if _, ok := cache.Get("u"); ok {
+		t.Fatal("tombstone should not exists")
+		return
+	}
+	if len(removed) != 0 {
+		t.Fatal("tombstone should not notify ", removed)
+		return
+	}
+	stats := cache.Stats()
+	if stats.Expired != 0 || stats.Size != 1 {
+		t.Fatal("tombstone stats error ", stats)
+		return
+	}
+}
+
+func TestMemCacheImpl_NegativeTTLEvict(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize:   2,
+		Evict:       EvictLRU,
+		NegativeTTL: 10 * time.Second,
+	})
+	notFound := func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, ErrNotFound
+	}
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+
+	// 不存在的 key 不会淘汰已有的 key
+	_, _ = cache.GetOrLoad(context.Background(), "x", notFound)
+	_, _ = cache.GetOrLoad(context.Background(), "y", notFound)
+	for _, key := range []string{"a", "b"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Fatal("tombstone should not evict key ", key)
+			return
+		}
+	}
+	if _, err := cache.GetOrLoad(context.Background(), "x", notFound); err != ErrNotFound {
+		t.Fatal("tombstone should cached ", err)
+		return
+	}
+
+	// 覆盖墓碑时计入 LimitSize
+	_ = cache.Set("x", 3)
+	if cache.Size() != 2 {
+		t.Fatal("size error ", cache.Size())
+		return
+	}
+
+	// 未设置淘汰策略时，墓碑不计入 LimitSize
+	cache = NewRWMapCacheWithConfig(Config{LimitSize: 1, NegativeTTL: 10 * time.Second})
+	_, _ = cache.GetOrLoad(context.Background(), "x", notFound)
+	if err := cache.Set("a", 1); err != nil {
+		t.Fatal("tombstone should not count limit size ", err)
+		return
+	}
+	if err := cache.MSet(map[string]interface{}{"a": 2}, time.Minute); err != nil {
+		t.Fatal("mset should not count tombstone ", err)
+		return
+	}
+	if err := cache.MSet(map[string]interface{}{"x": 2}, time.Minute); err != ErrKeysOverLimitSize {
+		t.Fatal("mset overwrite tombstone should count limit size ", err)
+		return
+	}
+}
@@ -0,0 +1,67 @@
+package gocache
+
+import (
+	"sync/atomic"
+)
+
+// SetPinned 写入并固定 key，永久有效，不会被淘汰
+func (mem *MemCache) SetPinned(key string, value interface{}) error {
+	ev := newExpireValue(value, NoExpiration, mem.sizeof(key, value), mem.now())
+	ev.Pinned = true
+	return mem.write(key, ev)
+}
+
+// Pin 固定 key，固定后不会被淘汰，也不会过期，Delete 和 FlushAll 仍然可以删除
+// key 不存在或已过期返回 false
+func (mem *MemCache) Pin(key string) bool {
+	return mem.setPinned(key, true)
+}
+
+// Unpin 取消固定，重新参与淘汰，如果设置过 TTL 且已经过期，下次读取时删除
+func (mem *MemCache) Unpin(key string) bool {
+	return mem.setPinned(key, false)
+}
+
+func (mem *MemCache) setPinned(key string, pinned bool) bool {
+	changed := false
+	ev, ok := mem.updateValue(key, func(ev *expireValue) bool {
+		if ev.Pinned == pinned {
+			return false
+		}
+		ev.Pinned = pinned
+		changed = true
+		return true
+	})
+	if !ok {
+		return false
+	}
+	if !changed {
+		return true
+	}
+
+	delta := int64(1)
+	if !pinned {
+		delta = -1
+	}
+	atomic.AddInt64(&mem.pinned, delta)
+	mem.accountNamespace(key, 0, 0, delta)
+
+	mem.indexExpire(key, ev)
+
+	if mem.policy != nil {
+		if pinned {
+			mem.policy.Delete(key)
+		} else {
+			mem.policy.Insert(key)
+			mem.evict()
+		}
+	}
+	return true
+}
+
+func boolToInt64(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
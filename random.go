@@ -0,0 +1,109 @@
+package gocache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 默认采样数量，与 redis maxmemory-samples 一致
+const defaultRandomSamples = 5
+
+// RandomPolicy 随机采样淘汰
+// 每次随机抽取 samples 个 key，淘汰其中最久未访问的，samples <= 1 时为完全随机淘汰
+// 不维护全局访问顺序，访问开销比 LRU 小
+type RandomPolicy struct {
+	mutex   sync.Mutex
+	samples int
+	rand    *rand.Rand
+	keys    []string
+	items   map[string]*randomItem
+	tick    uint64
+}
+
+type randomItem struct {
+	index int
+	tick  uint64
+}
+
+func NewRandomPolicy(samples int) *RandomPolicy {
+	if samples < 1 {
+		samples = 1
+	}
+	r := RandomPolicy{
+		samples: samples,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		keys:    make([]string, 0),
+		items:   make(map[string]*randomItem),
+	}
+	return &r
+}
+
+func (r *RandomPolicy) Access(key string) {
+	r.mutex.Lock()
+	if item, ok := r.items[key]; ok {
+		r.tick++
+		item.tick = r.tick
+	}
+	r.mutex.Unlock()
+}
+
+func (r *RandomPolicy) Insert(key string) {
+	r.mutex.Lock()
+	r.tick++
+	if item, ok := r.items[key]; ok {
+		item.tick = r.tick
+	} else {
+		r.items[key] = &randomItem{index: len(r.keys), tick: r.tick}
+		r.keys = append(r.keys, key)
+	}
+	r.mutex.Unlock()
+}
+
+func (r *RandomPolicy) Delete(key string) {
+	r.mutex.Lock()
+	r.remove(key)
+	r.mutex.Unlock()
+}
+
+// Evict 随机采样，淘汰样本中最久未访问的 key
+func (r *RandomPolicy) Evict() (key string, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.keys) == 0 {
+		return "", false
+	}
+	var oldest *randomItem
+	for i := 0; i < r.samples; i++ {
+		k := r.keys[r.rand.Intn(len(r.keys))]
+		item := r.items[k]
+		if oldest == nil || item.tick < oldest.tick {
+			oldest = item
+			key = k
+		}
+	}
+	r.remove(key)
+	return key, true
+}
+
+func (r *RandomPolicy) Flush() {
+	r.mutex.Lock()
+	r.keys = make([]string, 0)
+	r.items = make(map[string]*randomItem)
+	r.mutex.Unlock()
+}
+
+// remove 与最后一个元素交换后删除，O(1)
+func (r *RandomPolicy) remove(key string) {
+	item, ok := r.items[key]
+	if !ok {
+		return
+	}
+	last := len(r.keys) - 1
+	lastKey := r.keys[last]
+	r.keys[item.index] = lastKey
+	r.items[lastKey].index = item.index
+	r.keys = r.keys[:last]
+	delete(r.items, key)
+}
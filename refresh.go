@@ -0,0 +1,135 @@
+package gocache
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// 后台刷新默认并发数
+	defaultRefreshWorkers = 4
+	// 等待刷新的 key 队列长度，队列满时放弃本次刷新，下次读取时重试
+	refreshQueueSize = 1024
+)
+
+// refresher 后台刷新 GetOrLoad 加载的 key，worker 数量固定，Close 时停止
+type refresher struct {
+	// key - Loader，刷新时使用的 loader
+	loaders sync.Map
+
+	mutex   sync.Mutex
+	pending map[string]struct{}
+	closed  bool
+	tasks   chan string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newRefresher() *refresher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &refresher{
+		pending: make(map[string]struct{}),
+		tasks:   make(chan string, refreshQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// schedule 加入刷新队列，同一个 key 刷新完成前只加入一次
+func (r *refresher) schedule(key string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return
+	}
+	if _, ok := r.pending[key]; ok {
+		return
+	}
+	select {
+	case r.tasks <- key:
+		r.pending[key] = struct{}{}
+	default:
+	}
+}
+
+func (r *refresher) done(key string) {
+	r.mutex.Lock()
+	delete(r.pending, key)
+	r.mutex.Unlock()
+}
+
+func (r *refresher) forget(key string) {
+	r.loaders.Delete(key)
+}
+
+func (r *refresher) flush() {
+	r.loaders.Range(func(k, v interface{}) bool {
+		r.loaders.Delete(k)
+		return true
+	})
+}
+
+// stop 取消正在执行的 loader 的 ctx，等待 worker 退出
+func (r *refresher) stop() {
+	r.mutex.Lock()
+	r.closed = true
+	r.mutex.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (mem *MemCache) startRefreshWorkers(n int) {
+	if n <= 0 {
+		n = defaultRefreshWorkers
+	}
+	for i := 0; i < n; i++ {
+		mem.refresher.wg.Add(1)
+		go mem.refreshWorker()
+	}
+}
+
+func (mem *MemCache) refreshWorker() {
+	r := mem.refresher
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case key := <-r.tasks:
+			mem.refreshKey(key)
+			r.done(key)
+		}
+	}
+}
+
+// refreshKey 与 GetOrLoad 共享同一个 key 的加载，失败时保留当前值
+func (mem *MemCache) refreshKey(key string) {
+	v, ok := mem.refresher.loaders.Load(key)
+	if !ok {
+		return
+	}
+	loader := v.(Loader)
+	ctx := mem.refresher.ctx
+	_, _ = mem.loads.do(ctx, key, func() (interface{}, error) {
+		start := mem.now()
+		value, ttl, err := loader(ctx)
+		if err == ErrNotFound {
+			return nil, mem.setNotFound(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return loadResult{value: value}, mem.setLoaded(key, value, ttl, mem.now()-start, loader)
+	})
+}
+
+// needRefresh 读取到达刷新时间的 key 时，后台刷新
+func (mem *MemCache) needRefresh(key string, ev expireValue, now int64) {
+	if mem.refresher != nil && ev.RefreshAt > 0 && now >= ev.RefreshAt {
+		mem.refresher.schedule(key)
+	}
+}
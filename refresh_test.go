@@ -0,0 +1,120 @@
+package gocache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_RefreshAfter(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		Clock:        clock,
+		RefreshAfter: time.Minute,
+	})
+	defer cache.Close()
+
+	var calls int64
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			return "v1", time.Hour, nil
+		}
+		<-release
+		return "v2", time.Hour, nil
+	}
+
+	v, err := cache.GetOrLoad(context.Background(), "key", loader)
+	if err != nil || v.(string) != "v1" {
+		t.Fatal("load error ", v, err)
+		return
+	}
+
+	// 没到刷新时间
+	clock.Advance(30 * time.Second)
+	_, _ = cache.Get("key")
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatal("should not refresh ", calls)
+		return
+	}
+
+	// 到达刷新时间后读取返回当前值，只刷新一次
+	clock.Advance(time.Minute)
+	for i := 0; i < 10; i++ {
+		if v, ok := cache.Get("key"); !ok || v.(string) != "v1" {
+			t.Fatal("should return current value ", v)
+			return
+		}
+	}
+	if !waitFor(func() bool { return atomic.LoadInt64(&calls) == 2 }) {
+		t.Fatal("should refresh ", calls)
+		return
+	}
+	close(release)
+	if !waitFor(func() bool { v, _ := cache.Get("key"); return v.(string) == "v2" }) {
+		t.Fatal("refresh value error")
+		return
+	}
+	if atomic.LoadInt64(&calls) != 2 {
+		t.Fatal("refresh should dedup ", calls)
+		return
+	}
+	if ttl, _ := cache.TTL("key"); ttl != time.Hour {
+		t.Fatal("refresh should reset ttl ", ttl)
+		return
+	}
+
+	// Set 覆盖后不再刷新
+	_ = cache.Set("key", "v3")
+	clock.Advance(2 * time.Minute)
+	_, _ = cache.Get("key")
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt64(&calls) != 2 {
+		t.Fatal("set value should not refresh ", calls)
+		return
+	}
+}
+
+func TestMemCacheImpl_RefreshClose(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:      -1,
+		Clock:          clock,
+		RefreshAfter:   time.Minute,
+		RefreshWorkers: 1,
+	})
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	loaded := false
+	_, _ = cache.GetOrLoad(context.Background(), "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+		if !loaded {
+			loaded = true
+			return "v1", NoExpiration, nil
+		}
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return nil, 0, ctx.Err()
+	})
+
+	clock.Advance(2 * time.Minute)
+	_, _ = cache.Get("key")
+	<-started
+
+	// Close 取消正在执行的刷新并等待 worker 退出
+	cache.Close()
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("refresh should canceled by close")
+		return
+	}
+	if v, ok := cache.Get("key"); !ok || v.(string) != "v1" {
+		t.Fatal("failed refresh should keep value ", v)
+		return
+	}
+	cache.Close()
+}
@@ -0,0 +1,89 @@
+package gocache
+
+import (
+	"reflect"
+)
+
+// Sizer 估算 value 占用的内存字节数
+type Sizer func(value interface{}) int64
+
+// DefaultSizer 反射估算 value 占用的内存，包含 string、[]byte、slice、map、struct 及指针指向的内容
+// 不计算 map 桶、内存对齐等额外开销，结果偏小，仅作为容量控制的参考
+func DefaultSizer(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(cap(v))
+	}
+
+	rv := reflect.ValueOf(value)
+	return int64(rv.Type().Size()) + indirectSize(rv, make(map[uintptr]bool))
+}
+
+// indirectSize 值之外，通过指针引用的内存大小，seen 防止循环引用重复计算
+func indirectSize(v reflect.Value, seen map[uintptr]bool) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if isFlatKind(v.Type().Elem().Kind()) {
+			return size
+		}
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), seen)
+		}
+		return size
+	case reflect.Array:
+		if isFlatKind(v.Type().Elem().Kind()) {
+			return 0
+		}
+		size := int64(0)
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), seen)
+		}
+		return size
+	case reflect.Struct:
+		size := int64(0)
+		for i := 0; i < v.NumField(); i++ {
+			size += indirectSize(v.Field(i), seen)
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() {
+			return 0
+		}
+		kvSize := int64(v.Type().Key().Size() + v.Type().Elem().Size())
+		size := int64(0)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += kvSize + indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+		}
+		return size
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + indirectSize(elem, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + indirectSize(elem, seen)
+	}
+	return 0
+}
+
+// isFlatKind 不包含指针的基础类型
+func isFlatKind(kind reflect.Kind) bool {
+	return kind >= reflect.Bool && kind <= reflect.Complex128
+}
@@ -0,0 +1,57 @@
+package gocache
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestDefaultSizer(t *testing.T) {
+	type user struct {
+		Name string
+		Tags []string
+		Meta map[string]int
+	}
+
+	if size := DefaultSizer("hello"); size != 5 {
+		t.Fatal("string size error ", size)
+		return
+	}
+	if size := DefaultSizer(make([]byte, 10, 16)); size != 16 {
+		t.Fatal("[]byte size error ", size)
+		return
+	}
+	if size := DefaultSizer([]int64{1, 2, 3}); size != int64(unsafe.Sizeof([]int64{}))+24 {
+		t.Fatal("[]int64 size error ", size)
+		return
+	}
+
+	u := user{
+		Name: "gocache",
+		Tags: []string{"a", "bc"},
+		Meta: map[string]int{"age": 1},
+	}
+	strSize := int64(unsafe.Sizeof(""))
+	want := int64(unsafe.Sizeof(u)) +
+		7 +
+		2*strSize + 3 +
+		strSize + int64(unsafe.Sizeof(0)) + 3
+	if size := DefaultSizer(u); size != want {
+		t.Fatal("struct size error ", size, want)
+		return
+	}
+	if size := DefaultSizer(&u); size != want+int64(unsafe.Sizeof(&u)) {
+		t.Fatal("pointer size error ", size)
+		return
+	}
+
+	// 循环引用
+	type node struct {
+		Next *node
+	}
+	n := &node{}
+	n.Next = n
+	if size := DefaultSizer(n); size != 2*int64(unsafe.Sizeof(n)) {
+		t.Fatal("cycle size error ", size)
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_StaleGrace(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		Clock:       clock,
+		StaleGrace:  time.Minute,
+		ExpireIndex: true,
+	})
+
+	errDB := errors.New("db error")
+	failed := func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, errDB
+	}
+	_, _ = cache.GetOrLoad(context.Background(), "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+		return "v1", 10 * time.Second, nil
+	})
+
+	clock.Advance(20 * time.Second)
+	if _, ok := cache.Get("key"); ok {
+		t.Fatal("stale key should not exists")
+		return
+	}
+	if cache.expireClean() != 0 || cache.Size() != 1 {
+		t.Fatal("stale key should keep in grace ", cache.Size())
+		return
+	}
+
+	v, stale, err := cache.GetOrLoadStale(context.Background(), "key", failed)
+	if err != nil || !stale || v.(string) != "v1" {
+		t.Fatal("should return stale value ", v, stale, err)
+		return
+	}
+	v, err = cache.GetOrLoad(context.Background(), "key", failed)
+	if err != nil || v.(string) != "v1" {
+		t.Fatal("GetOrLoad should return stale value ", v, err)
+		return
+	}
+
+	// 宽限期结束
+	clock.Advance(time.Minute)
+	if _, stale, err = cache.GetOrLoadStale(context.Background(), "key", failed); err != errDB || stale {
+		t.Fatal("should return loader error after grace ", err)
+		return
+	}
+	// 读取时已删除
+	if cache.Size() != 0 || cache.Stats().Expired != 1 {
+		t.Fatal("stale key should remove after grace ", cache.Size())
+		return
+	}
+
+	// 加载成功不是旧值
+	v, stale, err = cache.GetOrLoadStale(context.Background(), "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+		return "v2", time.Minute, nil
+	})
+	if err != nil || stale || v.(string) != "v2" {
+		t.Fatal("load error ", v, stale, err)
+		return
+	}
+}
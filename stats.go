@@ -0,0 +1,29 @@
+package gocache
+
+import (
+	"sync/atomic"
+)
+
+// Stats 缓存统计
+type Stats struct {
+	Size      int64 // key 数量，包含固定的 key
+	Pinned    int64 // 固定的 key 数量
+	Cost      int64 // 总成本
+	Hits      int64 // 读取命中次数
+	Misses    int64 // 读取未命中次数，包含读取时已过期
+	Evictions int64 // 淘汰次数
+	Expired   int64 // 过期删除次数
+}
+
+// Stats 当前统计
+func (mem *MemCache) Stats() Stats {
+	return Stats{
+		Size:      mem.Size(),
+		Pinned:    atomic.LoadInt64(&mem.pinned),
+		Cost:      mem.Cost(),
+		Hits:      atomic.LoadInt64(&mem.hits),
+		Misses:    atomic.LoadInt64(&mem.misses),
+		Evictions: atomic.LoadInt64(&mem.evictions),
+		Expired:   atomic.LoadInt64(&mem.expired),
+	}
+}
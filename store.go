@@ -5,6 +5,50 @@ import (
 	"sync/atomic"
 )
 
+// checkedStore 写入可能失败的 Store(ByteStore 编码失败或 entry 过大)，失败时不写入并返回错误
+type checkedStore interface {
+	trySwap(key string, value interface{}) (previous interface{}, loaded bool, err error)
+	tryMStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool, err error)
+}
+
+// storeSwap 写入并返回写入前的值，Store 未实现 SwapStore 时用 LoadOrStore+Store 代替，调用方需要持有 key 锁
+func storeSwap(store Store, key string, value interface{}) (previous interface{}, loaded bool, err error) {
+	if cs, ok := store.(checkedStore); ok {
+		return cs.trySwap(key, value)
+	}
+	if ss, ok := store.(SwapStore); ok {
+		previous, loaded = ss.Swap(key, value)
+		return previous, loaded, nil
+	}
+	previous, loaded = store.LoadOrStore(key, value)
+	if !loaded {
+		return nil, false, nil
+	}
+	store.Store(key, value)
+	return previous, true, nil
+}
+
+// storeMStore 同 Store.MStore，checkedStore 写入失败时全部不写入并返回错误
+func storeMStore(store Store, values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool, err error) {
+	if cs, ok := store.(checkedStore); ok {
+		return cs.tryMStore(values, limit)
+	}
+	previous, ok = store.MStore(values, limit)
+	return previous, ok, nil
+}
+
+// storeLoadAndDelete 删除并返回删除前的值，Store 未实现 SwapStore 时用 Load+Delete 代替，调用方需要持有 key 锁
+func storeLoadAndDelete(store Store, key string) (value interface{}, loaded bool) {
+	if ss, ok := store.(SwapStore); ok {
+		return ss.LoadAndDelete(key)
+	}
+	value, loaded = store.Load(key)
+	if loaded {
+		store.Delete(key)
+	}
+	return value, loaded
+}
+
 // SyncMap 并发Map 当数据竞争大时，多核CPU时，使用比RwMap性能好，缺点空间占用会多点
 type SyncMap struct {
 	store sync.Map
@@ -44,6 +88,26 @@ func (s *SyncMap) LoadOrStore(key string, value interface{}) (actual interface{}
 	return v, loaded
 }
 
+// LoadAndDelete 与 Delete 一样非原子操作，并发删除同一个 key 时计数可能不准确
+func (s *SyncMap) LoadAndDelete(key string) (value interface{}, loaded bool) {
+	value, loaded = s.store.Load(key)
+	if loaded {
+		atomic.AddInt64(&s.size, -1)
+		s.store.Delete(key)
+	}
+	return value, loaded
+}
+
+func (s *SyncMap) Swap(key string, value interface{}) (previous interface{}, loaded bool) {
+	actual, loaded := s.store.LoadOrStore(key, value)
+	if !loaded {
+		atomic.AddInt64(&s.size, 1)
+		return nil, false
+	}
+	s.store.Store(key, value)
+	return actual, true
+}
+
 func (s *SyncMap) Exists(key string) bool {
 	_, ok := s.Load(key)
 	return ok
@@ -68,6 +132,48 @@ func (s *SyncMap) Size() int64 {
 	return size
 }
 
+func (s *SyncMap) MLoad(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := s.store.Load(key); ok {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+// MStore sync.Map 没有全局锁，limit 检查与并发的单个写入之间不是原子的
+func (s *SyncMap) MStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool) {
+	if limit >= 0 {
+		n := int64(0)
+		for key := range values {
+			if _, ok := s.store.Load(key); !ok {
+				n++
+			}
+		}
+		if s.Size()+n > limit {
+			return nil, false
+		}
+	}
+	previous = make(map[string]interface{})
+	for key, value := range values {
+		if v, loaded := s.Swap(key, value); loaded {
+			previous[key] = v
+		}
+	}
+	return previous, true
+}
+
+func (s *SyncMap) MDelete(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := s.LoadAndDelete(key); ok {
+			values[key] = v
+		}
+	}
+	return values
+}
+
 // RWMap 读写Map 当数据竞争不强，或读取多时。使用节省空间更快
 type RWMap struct {
 	rwMutex sync.RWMutex
@@ -121,6 +227,24 @@ func (s *RWMap) LoadOrStore(key string, value interface{}) (actual interface{},
 	return actual, loaded
 }
 
+func (s *RWMap) LoadAndDelete(key string) (value interface{}, loaded bool) {
+	s.rwMutex.Lock()
+	value, loaded = s.store[key]
+	delete(s.store, key)
+	s.rwMutex.Unlock()
+
+	return value, loaded
+}
+
+func (s *RWMap) Swap(key string, value interface{}) (previous interface{}, loaded bool) {
+	s.rwMutex.Lock()
+	previous, loaded = s.store[key]
+	s.store[key] = value
+	s.rwMutex.Unlock()
+
+	return previous, loaded
+}
+
 func (s *RWMap) Exists(key string) bool {
 	_, ok := s.Load(key)
 	return ok
@@ -144,8 +268,246 @@ func (s *RWMap) Flush() {
 }
 
 func (s *RWMap) Size() int64 {
-	s.rwMutex.Lock()
+	s.rwMutex.RLock()
 	size := len(s.store)
-	s.rwMutex.Unlock()
+	s.rwMutex.RUnlock()
 	return int64(size)
 }
+
+// MLoad 只加一次读锁
+func (s *RWMap) MLoad(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	s.rwMutex.RLock()
+	s.mload(keys, values)
+	s.rwMutex.RUnlock()
+	return values
+}
+
+// MStore 只加一次写锁，limit 检查和写入是原子的
+func (s *RWMap) MStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+
+	if limit >= 0 && int64(len(s.store))+s.newKeys(values) > limit {
+		return nil, false
+	}
+	previous = make(map[string]interface{})
+	s.mstore(values, previous)
+	return previous, true
+}
+
+func (s *RWMap) MDelete(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	s.rwMutex.Lock()
+	s.mdelete(keys, values)
+	s.rwMutex.Unlock()
+	return values
+}
+
+// mload、newKeys、mstore、mdelete 不加锁，由调用方持有锁，ShardedMap 复用
+func (s *RWMap) mload(keys []string, values map[string]interface{}) {
+	for _, key := range keys {
+		if v, ok := s.store[key]; ok {
+			values[key] = v
+		}
+	}
+}
+
+// newKeys 写入后新增的 key 数量
+func (s *RWMap) newKeys(values map[string]interface{}) int64 {
+	n := int64(0)
+	for key := range values {
+		if _, ok := s.store[key]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *RWMap) mstore(values, previous map[string]interface{}) {
+	for key, value := range values {
+		if v, ok := s.store[key]; ok {
+			previous[key] = v
+		}
+		s.store[key] = value
+	}
+}
+
+func (s *RWMap) mdelete(keys []string, values map[string]interface{}) {
+	for _, key := range keys {
+		if v, ok := s.store[key]; ok {
+			values[key] = v
+			delete(s.store, key)
+		}
+	}
+}
+
+// 默认分片数量
+const defaultShards = 32
+
+// ShardedMap 分片读写Map 按 key 哈希分散到多个独立加锁的 RWMap，减少写入时的锁竞争
+type ShardedMap struct {
+	shards []*RWMap
+	mask   uint32
+}
+
+// NewShardedMap shards 分片数量，向上取 2 的幂，<= 0 使用默认值 32
+func NewShardedMap(shards int) *ShardedMap {
+	if shards <= 0 {
+		shards = defaultShards
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	s := ShardedMap{
+		shards: make([]*RWMap, n),
+		mask:   uint32(n - 1),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewRWMap()
+	}
+	return &s
+}
+
+// shard FNV-1a 哈希选择分片
+func (s *ShardedMap) shard(key string) *RWMap {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return s.shards[hash&s.mask]
+}
+
+func (s *ShardedMap) Load(key string) (value interface{}, ok bool) {
+	return s.shard(key).Load(key)
+}
+
+func (s *ShardedMap) Store(key string, value interface{}) {
+	s.shard(key).Store(key, value)
+}
+
+func (s *ShardedMap) Delete(key string) {
+	s.shard(key).Delete(key)
+}
+
+func (s *ShardedMap) LoadOrStore(key string, value interface{}) (actual interface{}, loaded bool) {
+	return s.shard(key).LoadOrStore(key, value)
+}
+
+func (s *ShardedMap) LoadAndDelete(key string) (value interface{}, loaded bool) {
+	return s.shard(key).LoadAndDelete(key)
+}
+
+func (s *ShardedMap) Swap(key string, value interface{}) (previous interface{}, loaded bool) {
+	return s.shard(key).Swap(key, value)
+}
+
+func (s *ShardedMap) Exists(key string) bool {
+	return s.shard(key).Exists(key)
+}
+
+// Range 依次遍历每个分片，遍历某个分片时只持有该分片的读锁
+func (s *ShardedMap) Range(f func(k string, v interface{}) bool) {
+	next := true
+	for _, shard := range s.shards {
+		shard.Range(func(k string, v interface{}) bool {
+			next = f(k, v)
+			return next
+		})
+		if !next {
+			return
+		}
+	}
+}
+
+func (s *ShardedMap) Flush() {
+	for _, shard := range s.shards {
+		shard.Flush()
+	}
+}
+
+func (s *ShardedMap) Size() int64 {
+	size := int64(0)
+	for _, shard := range s.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// group 按分片分组，每个分片只加一次锁
+func (s *ShardedMap) group(keys []string) map[*RWMap][]string {
+	groups := make(map[*RWMap][]string)
+	for _, key := range keys {
+		shard := s.shard(key)
+		groups[shard] = append(groups[shard], key)
+	}
+	return groups
+}
+
+func (s *ShardedMap) MLoad(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for shard, keys := range s.group(keys) {
+		shard.rwMutex.RLock()
+		shard.mload(keys, values)
+		shard.rwMutex.RUnlock()
+	}
+	return values
+}
+
+// MStore limit >= 0 时按顺序锁住所有分片，保证 limit 检查和写入是原子的
+func (s *ShardedMap) MStore(values map[string]interface{}, limit int64) (previous map[string]interface{}, ok bool) {
+	groups := make(map[*RWMap]map[string]interface{})
+	for key, value := range values {
+		shard := s.shard(key)
+		if groups[shard] == nil {
+			groups[shard] = make(map[string]interface{})
+		}
+		groups[shard][key] = value
+	}
+
+	previous = make(map[string]interface{})
+	if limit < 0 {
+		for shard, values := range groups {
+			shard.rwMutex.Lock()
+			shard.mstore(values, previous)
+			shard.rwMutex.Unlock()
+		}
+		return previous, true
+	}
+
+	for _, shard := range s.shards {
+		shard.rwMutex.Lock()
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.rwMutex.Unlock()
+		}
+	}()
+
+	size := int64(0)
+	for _, shard := range s.shards {
+		size += int64(len(shard.store))
+	}
+	for shard, values := range groups {
+		size += shard.newKeys(values)
+	}
+	if size > limit {
+		return nil, false
+	}
+	for shard, values := range groups {
+		shard.mstore(values, previous)
+	}
+	return previous, true
+}
+
+func (s *ShardedMap) MDelete(keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for shard, keys := range s.group(keys) {
+		shard.rwMutex.Lock()
+		shard.mdelete(keys, values)
+		shard.rwMutex.Unlock()
+	}
+	return values
+}
@@ -1,6 +1,9 @@
 package gocache
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestRWMap(t *testing.T) {
 	rwMap := NewRWMap()
@@ -67,3 +70,163 @@ func TestRWMap(t *testing.T) {
 		return
 	}
 }
+
+func TestStore_SwapAndLoadAndDelete(t *testing.T) {
+	for _, s := range []Store{NewSyncMap(), NewRWMap(), NewShardedMap(4)} {
+		store := s.(interface {
+			Store
+			SwapStore
+		})
+		prev, loaded := store.Swap("swap", "1")
+		if loaded || prev != nil {
+			t.Fatal("swap should not loaded")
+			return
+		}
+		prev, loaded = store.Swap("swap", "2")
+		if !loaded || prev.(string) != "1" {
+			t.Fatal("swap previous error ", prev)
+			return
+		}
+		if store.Size() != 1 {
+			t.Fatal("swap size error ", store.Size())
+			return
+		}
+
+		v, loaded := store.LoadAndDelete("swap")
+		if !loaded || v.(string) != "2" {
+			t.Fatal("load and delete error ", v)
+			return
+		}
+		if _, loaded = store.LoadAndDelete("swap"); loaded {
+			t.Fatal("key should deleted")
+			return
+		}
+		if store.Size() != 0 {
+			t.Fatal("delete size error ", store.Size())
+			return
+		}
+	}
+}
+
+func TestShardedMap(t *testing.T) {
+	sm := NewShardedMap(3)
+	if len(sm.shards) != 4 {
+		t.Fatal("shards should round up to 4 ", len(sm.shards))
+		return
+	}
+
+	for i := 0; i < 100; i++ {
+		sm.Store(fmt.Sprintf("%d", i), i)
+	}
+	if sm.Size() != 100 {
+		t.Fatal("size error ", sm.Size())
+		return
+	}
+	v, ok := sm.Load("10")
+	if !ok || v.(int) != 10 {
+		t.Fatal("load error ", v)
+		return
+	}
+
+	keys := make(map[string]bool)
+	sm.Range(func(k string, v interface{}) bool {
+		keys[k] = true
+		return true
+	})
+	if len(keys) != 100 {
+		t.Fatal("range error ", len(keys))
+		return
+	}
+	count := 0
+	sm.Range(func(k string, v interface{}) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Fatal("range break error")
+		return
+	}
+
+	sm.Delete("10")
+	if sm.Exists("10") {
+		t.Fatal("10 should deleted")
+		return
+	}
+	sm.Flush()
+	if sm.Size() != 0 {
+		t.Fatal("flush error ", sm.Size())
+		return
+	}
+}
+
+func TestStore_Batch(t *testing.T) {
+	for _, store := range []Store{NewSyncMap(), NewRWMap(), NewShardedMap(4), NewByteStore(1<<20, nil)} {
+		_, _, _ = storeSwap(store, "a", "old")
+
+		prev, ok := store.MStore(map[string]interface{}{"a": "1", "b": "2", "c": "3"}, -1)
+		if !ok || len(prev) != 1 || prev["a"].(string) != "old" {
+			t.Fatalf("%T mstore previous error %v", store, prev)
+			return
+		}
+		values := store.MLoad([]string{"a", "b", "not-exists"})
+		if len(values) != 2 || values["a"].(string) != "1" || values["b"].(string) != "2" {
+			t.Fatalf("%T mload error %v", store, values)
+			return
+		}
+
+		// 超过 limit 全部不写入，覆盖已有的 key 不计入新增
+		if _, ok := store.MStore(map[string]interface{}{"c": "33", "d": "4", "e": "5"}, 4); ok {
+			t.Fatalf("%T mstore should over limit", store)
+			return
+		}
+		if store.Size() != 3 || store.Exists("d") {
+			t.Fatalf("%T over limit should not store %d", store, store.Size())
+			return
+		}
+		if _, ok := store.MStore(map[string]interface{}{"c": "33", "d": "4"}, 4); !ok || store.Size() != 4 {
+			t.Fatalf("%T mstore in limit error %d", store, store.Size())
+			return
+		}
+
+		removed := store.MDelete([]string{"a", "d", "not-exists"})
+		if len(removed) != 2 || removed["d"].(string) != "4" || store.Size() != 2 {
+			t.Fatalf("%T mdelete error %v %d", store, removed, store.Size())
+			return
+		}
+	}
+}
+
+type storeOnly interface {
+	Store
+}
+
+// plainStore 只实现 Store，不实现 SwapStore
+type plainStore struct {
+	storeOnly
+}
+
+func TestStore_WithoutSwapStore(t *testing.T) {
+	var store Store = plainStore{NewRWMap()}
+	if _, ok := store.(SwapStore); ok {
+		t.Fatal("plain store should not implement SwapStore")
+		return
+	}
+
+	cache := NewMemCacheWithConfig(store, Config{LimitSize: -1, LimitBytes: 1 << 20})
+	_ = cache.Set("a", "1")
+	_ = cache.Set("a", "22")
+	if v, ok := cache.Get("a"); !ok || v.(string) != "22" {
+		t.Fatal("set should overwrite ", v)
+		return
+	}
+	if cache.Size() != 1 || cache.Cost() != 3 {
+		t.Fatal("size or cost error ", cache.Size(), cache.Cost())
+		return
+	}
+
+	cache.Delete("a")
+	if cache.Size() != 0 || cache.Cost() != 0 {
+		t.Fatal("delete size or cost error ", cache.Size(), cache.Cost())
+		return
+	}
+}
@@ -0,0 +1,287 @@
+package gocache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+)
+
+const (
+	// LimitSize 不限制时，TinyLFU 估算的容量
+	defaultTinyLFUCapacity = 10000
+	// 窗口 LRU 占总容量 1%，主空间中保护区占 80%
+	tinyLFUWindowPercent    = 1
+	tinyLFUProtectedPercent = 80
+	// 计数器累计增加次数达到容量的倍数后，所有计数减半
+	tinyLFUResetFactor = 10
+	// 4bit 计数器上限
+	sketchMaxCount = 15
+	sketchDepth    = 4
+)
+
+const (
+	segmentWindow = iota
+	segmentProbation
+	segmentProtected
+)
+
+// TinyLFUPolicy W-TinyLFU 淘汰策略
+// 新 key 先进入窗口 LRU，窗口满后被挤出的 key 与主空间试用区的淘汰候选比较访问频率，频率低的被淘汰
+// 主空间为分段 LRU，试用区中再次被访问的 key 晋升保护区
+// 频率由 count-min sketch 估算并定期减半，只出现一次的扫描类 key 无法挤走热点 key
+type TinyLFUPolicy struct {
+	mutex  sync.Mutex
+	sketch *countMinSketch
+
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	items     map[string]*list.Element
+
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+type tinyLFUItem struct {
+	key     string
+	segment int
+}
+
+// NewTinyLFUPolicy capacity 预期的 key 数量，用于划分各区大小和 sketch 大小
+func NewTinyLFUPolicy(capacity int) *TinyLFUPolicy {
+	if capacity <= 0 {
+		capacity = defaultTinyLFUCapacity
+	}
+	windowCap := capacity * tinyLFUWindowPercent / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	protectedCap := mainCap * tinyLFUProtectedPercent / 100
+	if protectedCap < 1 {
+		protectedCap = 1
+	}
+
+	t := TinyLFUPolicy{
+		sketch:       newCountMinSketch(capacity),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		items:        make(map[string]*list.Element),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: protectedCap,
+	}
+	return &t
+}
+
+func (t *TinyLFUPolicy) Access(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.sketch.increment(key)
+	e, ok := t.items[key]
+	if !ok {
+		return
+	}
+
+	item := e.Value.(*tinyLFUItem)
+	switch item.segment {
+	case segmentWindow:
+		t.window.MoveToFront(e)
+	case segmentProbation:
+		// 试用区再次访问，晋升保护区，保护区超量时降级到试用区
+		t.probation.Remove(e)
+		item.segment = segmentProtected
+		t.items[key] = t.protected.PushFront(item)
+		if t.protected.Len() > t.protectedCap {
+			t.move(t.protected.Back(), t.probation, segmentProbation)
+		}
+	case segmentProtected:
+		t.protected.MoveToFront(e)
+	}
+}
+
+func (t *TinyLFUPolicy) Insert(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.sketch.increment(key)
+	if _, ok := t.items[key]; ok {
+		return
+	}
+	t.items[key] = t.window.PushFront(&tinyLFUItem{key: key, segment: segmentWindow})
+
+	// 主空间未满时，窗口挤出的 key 直接进入试用区，满了之后留给 Evict 比较频率
+	for t.window.Len() > t.windowCap && t.probation.Len()+t.protected.Len() < t.mainCap {
+		t.move(t.window.Back(), t.probation, segmentProbation)
+	}
+}
+
+func (t *TinyLFUPolicy) Delete(key string) {
+	t.mutex.Lock()
+	if e, ok := t.items[key]; ok {
+		t.segment(e.Value.(*tinyLFUItem).segment).Remove(e)
+		delete(t.items, key)
+	}
+	t.mutex.Unlock()
+}
+
+// Evict 窗口超量时，窗口淘汰候选与试用区淘汰候选比较频率，淘汰频率低的
+// 窗口未超量时，淘汰主空间的最久未访问 key
+func (t *TinyLFUPolicy) Evict() (key string, ok bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for t.window.Len() > t.windowCap {
+		candidate := t.window.Back()
+		victim := t.probation.Back()
+		if victim == nil {
+			victim = t.protected.Back()
+		}
+		if victim == nil {
+			// 主空间为空，直接进入试用区
+			t.move(candidate, t.probation, segmentProbation)
+			continue
+		}
+
+		candidateKey := candidate.Value.(*tinyLFUItem).key
+		victimKey := victim.Value.(*tinyLFUItem).key
+		if t.sketch.estimate(candidateKey) > t.sketch.estimate(victimKey) {
+			t.remove(victim)
+			t.move(candidate, t.probation, segmentProbation)
+			return victimKey, true
+		}
+		t.remove(candidate)
+		return candidateKey, true
+	}
+
+	for _, l := range []*list.List{t.probation, t.protected, t.window} {
+		if e := l.Back(); e != nil {
+			t.remove(e)
+			return e.Value.(*tinyLFUItem).key, true
+		}
+	}
+	return "", false
+}
+
+func (t *TinyLFUPolicy) Flush() {
+	t.mutex.Lock()
+	t.window.Init()
+	t.probation.Init()
+	t.protected.Init()
+	t.items = make(map[string]*list.Element)
+	t.sketch.clear()
+	t.mutex.Unlock()
+}
+
+func (t *TinyLFUPolicy) segment(segment int) *list.List {
+	switch segment {
+	case segmentProbation:
+		return t.probation
+	case segmentProtected:
+		return t.protected
+	}
+	return t.window
+}
+
+// move 移动到目标分段的头部
+func (t *TinyLFUPolicy) move(e *list.Element, to *list.List, segment int) {
+	item := e.Value.(*tinyLFUItem)
+	t.segment(item.segment).Remove(e)
+	item.segment = segment
+	t.items[item.key] = to.PushFront(item)
+}
+
+func (t *TinyLFUPolicy) remove(e *list.Element) {
+	item := e.Value.(*tinyLFUItem)
+	t.segment(item.segment).Remove(e)
+	delete(t.items, item.key)
+}
+
+// countMinSketch 频率估算，4 行 4bit(用 uint8 存储) 计数器，取最小值
+type countMinSketch struct {
+	rows [sketchDepth][]uint8
+	mask uint32
+	// 累计增加次数，达到 resetAt 时所有计数减半
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+	s := countMinSketch{
+		mask:    uint32(width - 1),
+		resetAt: capacity * tinyLFUResetFactor,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return &s
+}
+
+// indexes 双重哈希得到每行的位置
+func (s *countMinSketch) indexes(key string) [sketchDepth]uint32 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+
+	var idx [sketchDepth]uint32
+	for i := range idx {
+		idx[i] = (h1 + uint32(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch) increment(key string) {
+	idx := s.indexes(key)
+	added := false
+	for i, j := range idx {
+		if s.rows[i][j] < sketchMaxCount {
+			s.rows[i][j]++
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	idx := s.indexes(key)
+	min := uint8(sketchMaxCount)
+	for i, j := range idx {
+		if s.rows[i][j] < min {
+			min = s.rows[i][j]
+		}
+	}
+	return min
+}
+
+// reset 所有计数减半，让历史频率逐渐失效
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) clear() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+	s.additions = 0
+}
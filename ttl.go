@@ -0,0 +1,66 @@
+package gocache
+
+import (
+	"time"
+)
+
+// Expire 修改 key 的过期时间，不改写值，滑动过期的 key 改为固定过期时间
+// ttl <= 0 时 key 立即过期，key 不存在或已过期返回 false
+func (mem *MemCache) Expire(key string, ttl time.Duration) bool {
+	return mem.expireAt(key, mem.now()+int64(ttl))
+}
+
+// ExpireAt 修改 key 的过期时间为 at，at 早于当前时间时 key 立即过期
+func (mem *MemCache) ExpireAt(key string, at time.Time) bool {
+	return mem.expireAt(key, at.UnixNano())
+}
+
+func (mem *MemCache) expireAt(key string, expire int64) bool {
+	ev, ok := mem.updateValue(key, func(ev *expireValue) bool {
+		ev.Expire = expire
+		ev.Idle, ev.MaxExpire = 0, 0
+		return true
+	})
+	if !ok {
+		return false
+	}
+
+	now := mem.now()
+	if ev.isExpire(now) {
+		mem.removeExpired(key, now)
+		return true
+	}
+	mem.indexExpire(key, ev)
+	return true
+}
+
+// Persist 移除 key 的过期时间，永久有效，key 不存在或已过期返回 false
+func (mem *MemCache) Persist(key string) bool {
+	ev, ok := mem.updateValue(key, func(ev *expireValue) bool {
+		if ev.Expire == -1 {
+			return false
+		}
+		ev.Expire = -1
+		ev.Idle, ev.MaxExpire = 0, 0
+		return true
+	})
+	if ok {
+		mem.indexExpire(key, ev)
+	}
+	return ok
+}
+
+// TTL 剩余有效时间，永久有效为 NoExpiration，不算作访问，不会延长滑动过期
+func (mem *MemCache) TTL(key string) (time.Duration, bool) {
+	ev, ok := mem.peek(key)
+	if !ok || ev.Tombstone {
+		return 0, false
+	}
+	return ev.surplus(mem.now()), true
+}
+
+// Touch 访问 key 但不读取值，更新淘汰策略的访问记录，并延长滑动过期时间
+func (mem *MemCache) Touch(key string) bool {
+	_, ok := mem.getValue(key)
+	return ok
+}
@@ -0,0 +1,104 @@
+package gocache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_TTLCommands(t *testing.T) {
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:   -1,
+		ExpireIndex: true,
+	})
+	replaced := 0
+	cache.OnRemoved(func(key string, value interface{}, reason RemovalReason) {
+		if reason == RemovalReplaced {
+			replaced++
+		}
+	})
+
+	_ = cache.Set("key", "1")
+	if ttl, ok := cache.TTL("key"); !ok || ttl != NoExpiration {
+		t.Fatal("TTL should NoExpiration ", ttl)
+		return
+	}
+
+	if !cache.Expire("key", time.Minute) {
+		t.Fatal("expire error")
+		return
+	}
+	ttl, ok := cache.TTL("key")
+	if !ok || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatal("TTL error ", ttl)
+		return
+	}
+
+	if !cache.ExpireAt("key", time.Now().Add(time.Hour)) {
+		t.Fatal("expire at error")
+		return
+	}
+	if ttl, _ = cache.TTL("key"); ttl <= 59*time.Minute {
+		t.Fatal("expire at TTL error ", ttl)
+		return
+	}
+
+	if !cache.Persist("key") {
+		t.Fatal("persist error")
+		return
+	}
+	if ttl, _ = cache.TTL("key"); ttl != NoExpiration {
+		t.Fatal("persist TTL error ", ttl)
+		return
+	}
+
+	v, ok := cache.Get("key")
+	if !ok || v.(string) != "1" || replaced != 0 {
+		t.Fatal("value should not rewrite ", v, replaced)
+		return
+	}
+
+	if !cache.Expire("key", 0) {
+		t.Fatal("expire 0 error")
+		return
+	}
+	if _, ok := cache.Get("key"); ok {
+		t.Fatal("key should expired")
+		return
+	}
+	if cache.Expire("not-exists", time.Minute) || cache.Persist("not-exists") || cache.Touch("not-exists") {
+		t.Fatal("not exists key should false")
+		return
+	}
+	if _, ok := cache.TTL("not-exists"); ok {
+		t.Fatal("not exists key TTL should false")
+		return
+	}
+}
+
+func TestMemCacheImpl_Touch(t *testing.T) {
+	cache := NewRWMapCacheWithConfig(Config{
+		LimitSize: 2,
+		Evict:     EvictLRU,
+	})
+	_ = cache.SetWithIdleTimeout("session", "1", time.Minute, 0)
+	_ = cache.Set("other", "2")
+
+	ev, _ := cache.store.Load("session")
+	before := ev.(expireValue).Expire
+	if !cache.Touch("session") {
+		t.Fatal("touch error")
+		return
+	}
+	ev, _ = cache.store.Load("session")
+	if ev.(expireValue).Expire <= before {
+		t.Fatal("touch should extend idle timeout")
+		return
+	}
+
+	// touch 后 session 最近访问，淘汰 other
+	_ = cache.Set("new", "3")
+	if _, ok := cache.Get("other"); ok {
+		t.Fatal("other should evicted")
+		return
+	}
+}
@@ -0,0 +1,88 @@
+package gocache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestMemCacheImpl_XFetchProbability(t *testing.T) {
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		XFetchBeta:   1,
+		JitterSource: rand.NewSource(1),
+	})
+	now := cache.now()
+	ev := expireValue{Expire: now + int64(60*time.Second), Delta: int64(10 * time.Second)}
+
+	// 剩余 60s，概率 e^-6 约 0.25%
+	far := 0
+	for i := 0; i < 10000; i++ {
+		if cache.xfetch(ev, now) {
+			far++
+		}
+	}
+	// 剩余 1s，概率 e^-0.1 约 90%
+	near := 0
+	for i := 0; i < 10000; i++ {
+		if cache.xfetch(ev, ev.Expire-int64(time.Second)) {
+			near++
+		}
+	}
+	if far > 100 || near < 8500 || near > 9500 {
+		t.Fatal("xfetch probability error ", far, near)
+		return
+	}
+
+	// 没有加载耗时、永久有效不提前加载
+	for i := 0; i < 100; i++ {
+		if cache.xfetch(expireValue{Expire: now + 1}, now) || cache.xfetch(expireValue{Expire: -1, Delta: 1}, now) {
+			t.Fatal("should not xfetch")
+			return
+		}
+	}
+}
+
+func TestMemCacheImpl_XFetchGetOrLoad(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewSyncMapCacheWithConfig(Config{
+		LimitSize:    -1,
+		Clock:        clock,
+		XFetchBeta:   1,
+		JitterSource: rand.NewSource(1),
+	})
+
+	version := 0
+	loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+		// 加载耗时 1 分钟
+		clock.Advance(time.Minute)
+		version++
+		return version, time.Hour, nil
+	}
+	_, _ = cache.GetOrLoad(context.Background(), "key", loader)
+	ev, _ := cache.store.Load("key")
+	if ev.(expireValue).Delta != int64(time.Minute) {
+		t.Fatal("delta error ", ev.(expireValue).Delta)
+		return
+	}
+
+	// 接近过期，几乎一定提前加载
+	clock.Advance(time.Hour - time.Millisecond)
+	v, err := cache.GetOrLoad(context.Background(), "key", loader)
+	if err != nil || v.(int) != 2 {
+		t.Fatal("should load early ", v, err)
+		return
+	}
+
+	// 提前加载失败返回当前值
+	clock.Advance(time.Hour - time.Millisecond - time.Minute)
+	v, stale, err := cache.GetOrLoadStale(context.Background(), "key", func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, errors.New("db error")
+	})
+	if err != nil || stale || v.(int) != 2 {
+		t.Fatal("early load failed should return current value ", v, stale, err)
+		return
+	}
+}